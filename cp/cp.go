@@ -0,0 +1,556 @@
+//
+// cp.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Michael Murphy
+//
+
+// +build linux
+
+package main
+
+import "bufio"
+import "flag"
+import "fmt"
+import "os"
+import "path/filepath"
+import "strings"
+import "syscall"
+
+import "github.com/aisola/go-coreutils/internal/backup"
+import "github.com/aisola/go-coreutils/internal/cli"
+import "github.com/aisola/go-coreutils/internal/filecopy"
+
+const (
+	help_text string = `
+    Usage: cp [OPTION]... [-T] SOURCE DEST
+       or: cp [OPTION]... SOURCE... DIRECTORY
+       or: cp [OPTION]... -t DIRECTORY SOURCE...
+
+    Copy SOURCE to DEST, or multiple SOURCE(s) to DIRECTORY.
+
+        -help         display this help and exit
+        -version      output version information and exit
+
+        -a, -archive  same as -d -R -preserve=all
+        -backup[=CONTROL]
+                      make a backup of each existing destination file
+        -b            like -backup but does not accept an argument
+        -d            same as -no-dereference -preserve=links
+        -f, -force    if an existing destination file cannot be opened,
+                      remove it and try again (this option is ignored when
+                      the -n option is also used)
+        -i, -interactive
+                      prompt before overwrite (overrides a previous -n)
+        -H            follow command-line symbolic links in SOURCE
+        -l, -link     hard link files instead of copying
+        -L, -dereference
+                      always follow symbolic links in SOURCE
+        -n, -no-clobber
+                      do not overwrite an existing file (overrides a
+                      previous -i)
+        -P, -no-dereference
+                      never follow symbolic links in SOURCE
+        -p            same as -preserve=mode,ownership,timestamps
+        -preserve[=ATTR_LIST]
+                      preserve the specified attributes (default:
+                      mode,ownership,timestamps), if possible; additional
+                      attributes: links, xattr, all
+        -R, -r, -recursive
+                      copy directories recursively
+        -reflink[=WHEN]
+                      control clone/CoW copies; see below
+        -sparse=WHEN  control creation of sparse files; see below
+        -s, -symbolic-link
+                      make symbolic links instead of copying
+        -S, -suffix=SUFFIX
+                      override the usual backup suffix
+        -t, -target-directory=DIRECTORY
+                      copy all SOURCE arguments into DIRECTORY
+        -T, -no-target-directory
+                      treat DEST as a normal file
+        -u, -update   copy only when the SOURCE file is newer than the
+                      destination file or when the destination file is
+                      missing
+        -v, -verbose  explain what is being done
+
+    By default, sparse SOURCE files are detected by the blocks they occupy
+    and the corresponding DEST file is made sparse as well. That is what
+    -sparse=auto selects. -sparse=always makes DEST sparse wherever SOURCE
+    has a block of zeros, and -sparse=never writes every block.
+
+    With -reflink or -reflink=always, the data of each file is cloned, so
+    that its blocks are shared until either copy is changed, and the copy
+    fails where the file system cannot clone. -reflink=auto, the default,
+    falls back to an ordinary copy, and -reflink=never always makes one.
+
+    The backup suffix is '~', unless set with -suffix or SIMPLE_BACKUP_SUFFIX.
+    The version control method may be selected via the -backup option or
+    through the VERSION_CONTROL environment variable. Here are the values:
+
+        none, off       never make backups (even if -backup is given)
+        numbered, t     make numbered backups
+        existing, nil   numbered if numbered backups exist, simple otherwise
+        simple, never   always make simple backups
+`
+	version_text = `
+    cp (go-coreutils) 0.1
+
+    Copyright (C) 2014, The GO-Coreutils Developers.
+    This program comes with ABSOLUTELY NO WARRANTY; for details see
+    LICENSE. This is free software, and you are welcome to redistribute
+    it under certain conditions in LICENSE.
+`
+)
+
+var (
+	archive               = flag.Bool("a", false, "same as -d -R -preserve=all")
+	archiveLong           = flag.Bool("archive", false, "same as -d -R -preserve=all")
+	makeBackup            = flag.Bool("b", false, "like -backup but does not accept an argument")
+	noDereferenceLinks    = flag.Bool("d", false, "same as -no-dereference -preserve=links")
+	forceEnabled          = flag.Bool("f", false, "remove an existing destination that cannot be opened and try again")
+	forceEnabledLong      = flag.Bool("force", false, "remove an existing destination that cannot be opened and try again")
+	interactive           = flag.Bool("i", false, "prompt before overwrite")
+	interactiveLong       = flag.Bool("interactive", false, "prompt before overwrite")
+	commandLineLinks      = flag.Bool("H", false, "follow command-line symbolic links in SOURCE")
+	hardLink              = flag.Bool("l", false, "hard link files instead of copying")
+	hardLinkLong          = flag.Bool("link", false, "hard link files instead of copying")
+	dereference           = flag.Bool("L", false, "always follow symbolic links in SOURCE")
+	dereferenceLong       = flag.Bool("dereference", false, "always follow symbolic links in SOURCE")
+	noClobber             = flag.Bool("n", false, "do not overwrite an existing file")
+	noClobberLong         = flag.Bool("no-clobber", false, "do not overwrite an existing file")
+	noDereference         = flag.Bool("P", false, "never follow symbolic links in SOURCE")
+	noDereferenceLong     = flag.Bool("no-dereference", false, "never follow symbolic links in SOURCE")
+	preserveDefault       = flag.Bool("p", false, "same as -preserve=mode,ownership,timestamps")
+	recursive             = flag.Bool("R", false, "copy directories recursively")
+	recursiveShort        = flag.Bool("r", false, "copy directories recursively")
+	recursiveLong         = flag.Bool("recursive", false, "copy directories recursively")
+	sparse                = flag.String("sparse", "auto", "control creation of sparse files: auto, always or never")
+	symbolicLink          = flag.Bool("s", false, "make symbolic links instead of copying")
+	symbolicLinkLong      = flag.Bool("symbolic-link", false, "make symbolic links instead of copying")
+	suffix                = flag.String("S", "", "override the usual backup suffix")
+	suffixLong            = flag.String("suffix", "", "override the usual backup suffix")
+	targetDirectory       = flag.String("t", "", "copy all SOURCE arguments into DIRECTORY")
+	targetDirectoryLong   = flag.String("target-directory", "", "copy all SOURCE arguments into DIRECTORY")
+	noTargetDirectory     = flag.Bool("T", false, "treat DEST as a normal file")
+	noTargetDirectoryLong = flag.Bool("no-target-directory", false, "treat DEST as a normal file")
+	updateOlder           = flag.Bool("u", false, "copy only when the SOURCE file is newer than the destination file")
+	updateOlderLong       = flag.Bool("update", false, "copy only when the SOURCE file is newer than the destination file")
+	verbose               = flag.Bool("v", false, "explain what is being done")
+	verboseLong           = flag.Bool("verbose", false, "explain what is being done")
+	help                  = flag.Bool("help", false, "display help information")
+	version               = flag.Bool("version", false, "display version information")
+
+	backupControl cli.OptionalValue
+	preserve      cli.OptionalValue
+	reflink       cli.OptionalValue
+)
+
+// How an existing destination is treated, set by the last of -i and -n.
+const (
+	OVERWRITE_DEFAULT = iota // Replace it
+	OVERWRITE_PROMPT         // Prompt before replacing it
+	OVERWRITE_NEVER          // Never replace it
+)
+
+var overwriteMode = OVERWRITE_DEFAULT
+var backupType = backup.NONE
+var options filecopy.Options
+
+// The status to exit with, set when any copy fails.
+var status = 0
+
+// The backups made of the destinations, for -verbose to report.
+var backups = map[string]string{}
+
+// The flags that take their value from the following argument.
+var valueFlags = map[string]bool{"S": true, "suffix": true, "t": true, "target-directory": true, "sparse": true}
+
+/* getOverwriteMode returns the mode of the last of -i and -n on the command
+ * line. The flag package does not keep the order of flags, which decides
+ * which of them, and of -L, -H and -P, takes effect. */
+func getOverwriteMode() int {
+	mode := OVERWRITE_DEFAULT
+	for _, given := range cli.GivenFlags(os.Args[1:], valueFlags) {
+		switch given.Name {
+		case "i", "interactive":
+			mode = OVERWRITE_PROMPT
+		case "n", "no-clobber":
+			mode = OVERWRITE_NEVER
+		}
+	}
+	return mode
+}
+
+/* getDereference returns which symbolic links to follow, as set by the last
+ * of -L, -H and -P, or of -a and -d, which imply -P. Without any of them,
+ * links are not followed in a recursive copy, and are otherwise. */
+func getDereference() int {
+	dereference := -1
+	for _, given := range cli.GivenFlags(os.Args[1:], valueFlags) {
+		switch given.Name {
+		case "L", "dereference":
+			dereference = filecopy.DEREF_ALWAYS
+		case "H":
+			dereference = filecopy.DEREF_COMMAND_LINE
+		case "P", "no-dereference", "d", "a", "archive":
+			dereference = filecopy.DEREF_NEVER
+		}
+	}
+	if dereference < 0 && options.Recursive && !options.HardLink {
+		return filecopy.DEREF_NEVER
+	} else if dereference < 0 {
+		return filecopy.DEREF_ALWAYS
+	}
+	return dereference
+}
+
+// invalidArgument reports a value the option does not take, listing those
+// that it does, and exits.
+func invalidArgument(value, option string, valid ...string) {
+	list := ""
+	for _, name := range valid {
+		list += fmt.Sprintf("\n  - '%s'", name)
+	}
+	cli.UsageError("cp", "invalid argument '%s' for '--%s'\nValid arguments are:%s", value, option, list)
+}
+
+// The answers to prompts, read a line at a time.
+var stdin = bufio.NewReader(os.Stdin)
+
+// The input function prints a prompt to the user on standard error and
+// returns true if the answer is yes.
+func input(format string, a ...interface{}) bool {
+	fmt.Fprintf(os.Stderr, "cp: "+format, a...)
+	answer, _ := stdin.ReadString('\n')
+	return strings.HasPrefix(answer, "y") || strings.HasPrefix(answer, "Y")
+}
+
+// What GNU cp says has failed, for each operation of a path error.
+var pathMessages = map[string]string{
+	"stat":      "cannot stat '%s'",
+	"lstat":     "cannot stat '%s'",
+	"open":      "cannot open '%s' for reading",
+	"read":      "error reading '%s'",
+	"readdir":   "cannot access '%s'",
+	"readlink":  "cannot read symbolic link '%s'",
+	"create":    "cannot create regular file '%s'",
+	"write":     "error writing '%s'",
+	"truncate":  "failed to extend '%s'",
+	"mkdir":     "cannot create directory '%s'",
+	"mknod":     "cannot create special file '%s'",
+	"unlink":    "cannot remove '%s'",
+	"lchown":    "failed to preserve ownership for '%s'",
+	"chmod":     "preserving permissions for '%s'",
+	"utimensat": "preserving times for '%s'",
+	"listxattr": "failed to preserve extended attributes of '%s'",
+	"getxattr":  "failed to preserve extended attributes of '%s'",
+	"setxattr":  "failed to preserve extended attributes for '%s'",
+}
+
+// Returns the message to report for an error from the copy, in the words
+// of GNU cp.
+func message(err error) string {
+	switch e := err.(type) {
+	case *os.PathError:
+		if format, ok := pathMessages[e.Op]; ok {
+			return fmt.Sprintf(format+": %s", e.Path, cli.ErrorString(e))
+		}
+	case *os.LinkError:
+		switch e.Op {
+		case "link":
+			return fmt.Sprintf("cannot create hard link '%s' to '%s': %s", e.New, e.Old, cli.ErrorString(e))
+		case "symlink":
+			return fmt.Sprintf("cannot create symbolic link '%s' to '%s': %s", e.New, e.Old, cli.ErrorString(e))
+		case "clone":
+			return fmt.Sprintf("failed to clone '%s' from '%s': %s", e.New, e.Old, cli.ErrorString(e))
+		}
+	}
+	return err.Error()
+}
+
+// report prints the error, if any, and marks the copy as failed.
+func report(err error) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cp: %s\n", message(err))
+		status = 1
+	}
+}
+
+/* Returns true if copying the source would write over the source itself:
+ * if the destination is the same file or, unless a link is copied as a
+ * link, is a symbolic link to it. */
+func sameFile(sourceStat *syscall.Stat_t, destination string, destinationStat *syscall.Stat_t) bool {
+	if sourceStat.Dev == destinationStat.Dev && sourceStat.Ino == destinationStat.Ino {
+		return true
+	}
+	var target syscall.Stat_t
+	return destinationStat.Mode&syscall.S_IFMT == syscall.S_IFLNK &&
+		sourceStat.Mode&syscall.S_IFMT != syscall.S_IFLNK && syscall.Stat(destination, &target) == nil &&
+		sourceStat.Dev == target.Dev && sourceStat.Ino == target.Ino
+}
+
+// Returns true if the source was modified after the destination.
+func isNewer(sourceStat, destinationStat *syscall.Stat_t) bool {
+	source, destination := sourceStat.Mtim, destinationStat.Mtim
+	return source.Sec > destination.Sec || (source.Sec == destination.Sec && source.Nsec > destination.Nsec)
+}
+
+/* shouldReplace decides whether an existing destination is replaced, as
+ * -n, -update and -i ask, prompting the user where needed, and makes the
+ * backup of it that -backup asks for. */
+func shouldReplace(source, destination string, sourceStat, destinationStat *syscall.Stat_t) (bool, error) {
+	switch {
+	case overwriteMode == OVERWRITE_NEVER:
+		return false, nil
+	case sameFile(sourceStat, destination, destinationStat):
+		return false, fmt.Errorf("'%s' and '%s' are the same file", source, destination)
+	case *updateOlder && !isNewer(sourceStat, destinationStat):
+		return false, nil
+	case overwriteMode == OVERWRITE_PROMPT && !input("overwrite '%s'? ", destination):
+		return false, nil
+	}
+
+	if backupName := backup.Name(destination, backupType, backup.Suffix(*suffix)); backupName != "" {
+		if err := os.Rename(destination, backupName); err != nil {
+			return false, fmt.Errorf("cannot backup '%s': %s", destination, cli.ErrorString(err))
+		}
+		backups[destination] = backupName
+	}
+	return true, nil
+}
+
+// copied reports each file made, as -verbose asks.
+func copied(source, destination string) {
+	if !*verbose {
+		return
+	}
+	if backupName, ok := backups[destination]; ok {
+		fmt.Printf("'%s' -> '%s' (backup: '%s')\n", source, destination, backupName)
+	} else {
+		fmt.Printf("'%s' -> '%s'\n", source, destination)
+	}
+}
+
+/* The copier function copies the source to the destination, which is taken
+ * as the final name of the source, and reports any errors. The copier is
+ * shared by every source, so that hard links between them are kept. */
+func copier(c *filecopy.Copier, source, destination string) {
+	var stat syscall.Stat_t
+	err := syscall.Lstat(source, &stat)
+	if err == nil && options.Dereference != filecopy.DEREF_NEVER {
+		err = syscall.Stat(source, &stat)
+	}
+	if err == nil && stat.Mode&syscall.S_IFMT == syscall.S_IFDIR {
+		if !options.Recursive {
+			report(fmt.Errorf("-r not specified; omitting directory '%s'", source))
+			return
+		}
+		if cli.IsInside(destination, source) {
+			report(fmt.Errorf("cannot copy a directory, '%s', into itself, '%s'", source, destination))
+			return
+		}
+	}
+	if options.SymbolicLink && !filepath.IsAbs(source) && filepath.Dir(destination) != "." {
+		report(fmt.Errorf("%s: can make relative symbolic links only in current directory", destination))
+		return
+	}
+	report(c.Copy(source, destination))
+}
+
+/* The argumentCheck function checks the operands and copies each source,
+ * returning the exit status. Errors are reported as they happen, and the
+ * remaining sources are still copied. */
+func argumentCheck(files []string) int {
+	c := filecopy.New(options)
+
+	var sources []string
+	var directory string
+	switch {
+	case *targetDirectory != "" && *noTargetDirectory:
+		fmt.Fprintln(os.Stderr, "cp: cannot combine --target-directory (-t) and --no-target-directory (-T)")
+		return 1
+	case len(files) == 0:
+		cli.UsageError("cp", "missing file operand")
+	case *targetDirectory != "":
+		if info, err := os.Stat(*targetDirectory); err != nil {
+			fmt.Fprintf(os.Stderr, "cp: target directory '%s': %s\n", *targetDirectory, cli.ErrorString(err))
+			return 1
+		} else if !info.IsDir() {
+			fmt.Fprintf(os.Stderr, "cp: target directory '%s': Not a directory\n", *targetDirectory)
+			return 1
+		}
+		sources, directory = files, *targetDirectory
+	case len(files) == 1:
+		cli.UsageError("cp", "missing destination file operand after '%s'", files[0])
+	case *noTargetDirectory && len(files) > 2:
+		cli.UsageError("cp", "extra operand '%s'", files[2])
+	case *noTargetDirectory || (len(files) == 2 && !cli.IsDirectory(files[1])):
+		copier(c, files[0], files[1])
+		return status
+	default:
+		sources, directory = files[:len(files)-1], files[len(files)-1]
+		if info, err := os.Stat(directory); err != nil {
+			fmt.Fprintf(os.Stderr, "cp: target '%s': %s\n", directory, cli.ErrorString(err))
+			return 1
+		} else if !info.IsDir() {
+			fmt.Fprintf(os.Stderr, "cp: target '%s': Not a directory\n", directory)
+			return 1
+		}
+	}
+
+	for _, source := range sources {
+		copier(c, source, filepath.Join(directory, filepath.Base(source)))
+	}
+	return status
+}
+
+/* setPreserve sets the attributes to preserve from a list such as
+ * mode,timestamps, or from the default list if it is empty. */
+func setPreserve(list string) {
+	if list == "" {
+		list = "mode,ownership,timestamps"
+	}
+	for _, attribute := range strings.Split(list, ",") {
+		switch attribute {
+		case "mode":
+			options.Mode = true
+		case "ownership":
+			options.Ownership = true
+		case "timestamps":
+			options.Timestamps = true
+		case "links":
+			options.Links = true
+		case "xattr":
+			options.Xattr = true
+		case "all":
+			options.Mode, options.Ownership, options.Timestamps = true, true, true
+			options.Links, options.Xattr = true, true
+		default:
+			invalidArgument(attribute, "preserve", "mode", "timestamps", "ownership", "links", "xattr", "all")
+		}
+	}
+}
+
+func main() {
+	files := flag.Args() // Obtain a list of files.
+	os.Exit(argumentCheck(files))
+}
+
+func init() {
+	flag.Var(&backupControl, "backup", "make a backup of each existing destination file")
+	flag.Var(&preserve, "preserve", "preserve the specified attributes")
+	flag.Var(&reflink, "reflink", "control clone/CoW copies: auto, always or never")
+	flag.Parse()
+
+	if *help {
+		fmt.Print(help_text)
+		os.Exit(0)
+	}
+	if *version {
+		fmt.Print(version_text)
+		os.Exit(0)
+	}
+
+	if *archiveLong {
+		*archive = true
+	}
+	if *forceEnabledLong {
+		*forceEnabled = true
+	}
+	if *interactiveLong {
+		*interactive = true
+	}
+	if *hardLinkLong {
+		*hardLink = true
+	}
+	if *noClobberLong {
+		*noClobber = true
+	}
+	if *recursiveShort || *recursiveLong {
+		*recursive = true
+	}
+	if *symbolicLinkLong {
+		*symbolicLink = true
+	}
+	if *suffixLong != "" {
+		*suffix = *suffixLong
+	}
+	if *targetDirectoryLong != "" {
+		*targetDirectory = *targetDirectoryLong
+	}
+	if *noTargetDirectoryLong {
+		*noTargetDirectory = true
+	}
+	if *updateOlderLong {
+		*updateOlder = true
+	}
+	if *verboseLong {
+		*verbose = true
+	}
+	overwriteMode = getOverwriteMode()
+
+	options.Recursive = *recursive || *archive
+	options.HardLink = *hardLink
+	options.SymbolicLink = *symbolicLink
+	options.Force = *forceEnabled
+	if *archive {
+		setPreserve("all")
+	}
+	if *noDereferenceLinks {
+		options.Links = true
+	}
+	if *preserveDefault {
+		setPreserve("")
+	}
+	if preserve.Given {
+		setPreserve(preserve.Value)
+	}
+	options.Dereference = getDereference()
+
+	switch reflink.Value {
+	case "auto":
+		options.Reflink = filecopy.REFLINK_AUTO
+	case "", "always":
+		options.Reflink = filecopy.REFLINK_ALWAYS
+	case "never":
+		options.Reflink = filecopy.REFLINK_NEVER
+	default:
+		invalidArgument(reflink.Value, "reflink", "auto", "always", "never")
+	}
+	if !reflink.Given {
+		options.Reflink = filecopy.REFLINK_AUTO
+	}
+	switch *sparse {
+	case "auto":
+		options.Sparse = filecopy.SPARSE_AUTO
+	case "always":
+		options.Sparse = filecopy.SPARSE_ALWAYS
+	case "never":
+		options.Sparse = filecopy.SPARSE_NEVER
+	default:
+		invalidArgument(*sparse, "sparse", "never", "auto", "always")
+	}
+	if options.Reflink == filecopy.REFLINK_ALWAYS && options.Sparse != filecopy.SPARSE_AUTO {
+		cli.UsageError("cp", "--reflink can be used only with --sparse=auto")
+	}
+	if options.HardLink && options.SymbolicLink {
+		cli.UsageError("cp", "cannot make both hard and symbolic links")
+	}
+
+	// -S implies a backup, as in GNU cp.
+	if *makeBackup || backupControl.Given || *suffix != "" {
+		control, err := backup.ParseControl(backupControl.Value)
+		if err != nil {
+			cli.UsageError("cp", "%s for 'backup type'\n%s", err, backup.ValidArguments)
+		}
+		backupType = control
+	}
+	if backupType != backup.NONE && overwriteMode == OVERWRITE_NEVER {
+		cli.UsageError("cp", "options --backup and --no-clobber are mutually exclusive")
+	}
+
+	options.Exists = shouldReplace
+	options.Copied = copied
+	options.Errors = report
+}
@@ -6,11 +6,16 @@
 //
 package main
 
+import "bufio"
 import "flag"
 import "fmt"
+import "io"
 import "os"
+import "strings"
 import "time"
 
+import "github.com/aisola/go-coreutils/internal/parsedate"
+
 const (
 	RFC3339_DATE    = "2006-01-02"
 	RFC3339_SECONDS = "2006-01-02 03:04:05-07:00"
@@ -25,6 +30,16 @@ const (
 
 	Display the current time in the given FORMAT.
 
+	-d, -date=STRING
+	      display time described by STRING, not 'now'
+
+	-debug
+	      annotate the parsed date, and warn about questionable usage
+	      on standard error
+
+	-f, -file=DATEFILE
+	      like -date; once for each line of DATEFILE
+
 	-I [TIMESPEC], -iso-8601=[TIMESPEC]
 	        output date/time in ISO 8601 format. TIMESPEC='date' for date
 	        only, 'hours', 'minutes', 'seconds', or 'ns' for date and
@@ -37,6 +52,12 @@ const (
 	      output date and time in RFC 1123 format.
 	      Example: Thu, 19 Jun 2014 03:53:45 -0500
 
+	-s, -set=STRING
+	      set time described by STRING
+
+	-dry-run
+	      with -set, do everything but actually set the clock
+
 	-rfc-3339=[TIMESPEC]
 	      output date and time in RFC 3339 format.  TIMESPEC='date',
               'seconds', or 'ns' for date and time to the indicated precision.
@@ -49,6 +70,80 @@ const (
         -help display this help and exit
 
         -version output version information and exit
+
+	FORMAT controls the output. Interpreted sequences are:
+
+	%%   a literal %
+	%a   locale's abbreviated weekday name (e.g., Sun)
+	%A   locale's full weekday name (e.g., Sunday)
+	%b   locale's abbreviated month name (e.g., Jan)
+	%B   locale's full month name (e.g., January)
+	%c   locale's date and time (e.g., Thu Mar  3 23:05:25 2005)
+	%C   century; like %Y, except omit last two digits (e.g., 20)
+	%d   day of month (e.g., 01)
+	%D   date; same as %m/%d/%y
+	%e   day of month, space padded; same as %_d
+	%F   full date; same as %Y-%m-%d
+	%g   last two digits of year of ISO week number (see %G)
+	%G   year of ISO week number (see %V); normally useful only with %V
+	%h   same as %b
+	%H   hour (00..23)
+	%I   hour (01..12)
+	%j   day of year (001..366)
+	%k   hour, space padded ( 0..23); same as %_H
+	%l   hour, space padded ( 1..12); same as %_I
+	%m   month (01..12)
+	%M   minute (00..59)
+	%n   a newline
+	%N   nanoseconds (000000000..999999999)
+	%p   locale's equivalent of either AM or PM; blank if not known
+	%P   like %p, but lower case
+	%q   quarter of year (1..4)
+	%r   locale's 12-hour clock time (e.g., 11:11:04 PM)
+	%R   24-hour hour and minute; same as %H:%M
+	%s   seconds since 1970-01-01 00:00:00 UTC
+	%S   second (00..60)
+	%t   a tab
+	%T   time; same as %H:%M:%S
+	%u   day of week (1..7); 1 is Monday
+	%U   week number of year, with Sunday as first day of week (00..53)
+	%V   ISO week number, with Monday as first day of week (01..53)
+	%w   day of week (0..6); 0 is Sunday
+	%W   week number of year, with Monday as first day of week (00..53)
+	%x   locale's date representation (e.g., 12/31/99)
+	%X   locale's time representation (e.g., 23:13:48)
+	%y   last two digits of year (00..99)
+	%Y   year
+	%z   +hhmm numeric time zone (e.g., -0400)
+	%:z  +hh:mm numeric time zone (e.g., -04:00)
+	%::z  +hh:mm:ss numeric time zone (e.g., -04:00:00)
+	%:::z  numeric time zone with : to necessary precision (e.g., -04, +05:30)
+	%Z   alphabetic time zone abbreviation (e.g., EDT)
+
+	By default, date pads numeric fields with zeroes.
+	The following optional flags may follow '%':
+
+	-  (hyphen) do not pad the field
+	_  (underscore) pad with spaces
+	0  (zero) pad with zeros
+	^  use upper case if possible
+	#  use opposite case if possible
+
+	After any flags comes an optional field width, as a decimal number;
+	then an optional modifier, which is either
+	E to use the locale's alternate representations if available, or
+	O to use the locale's alternate numeric symbols if available.
+
+	The STRING of -date is mostly free format: it may hold calendar dates
+	(2014-06-19, 6/19/2014, 19 Jun 2014), times of day (03:55, 3:55pm,
+	03:55:49.5-05:00), time zones (UTC, EST, +0530), days of the week
+	(friday, next friday), relative items (yesterday, +3 hours, last month,
+	2 weeks ago), seconds since the epoch (@1403164549) and a leading
+	TZ="Zone" that sets the time zone the rest is interpreted in.
+
+	The TZ environment variable sets the time zone: a zone name such as
+	Europe/Paris, the path of a zoneinfo file, or a POSIX TZ string such
+	as EST5EDT,M3.2.0,M11.1.0.
 `
 	VERSION_TEXT = `
 	       date (go-coreutils) 0.1
@@ -71,31 +166,138 @@ var (
 	printRFC1123      = flag.Bool("R", false, "output date and time in RFC 2822 format.")
 	printRFC1123Long  = flag.Bool("rfc-1123", false, "output date and time in RFC 2822 format.")
 	printRFC3339      = flag.String("rfc-3339", "", "output date and time in RFC 3339 format: [date|seconds|ns]")
+	dateString        = flag.String("d", "", "display time described by STRING, not 'now'")
+	dateStringLong    = flag.String("date", "", "display time described by STRING, not 'now'")
+	dateFile          = flag.String("f", "", "like -date; once for each line of DATEFILE")
+	dateFileLong      = flag.String("file", "", "like -date; once for each line of DATEFILE")
+	setString         = flag.String("s", "", "set time described by STRING")
+	setStringLong     = flag.String("set", "", "set time described by STRING")
+	dryRun            = flag.Bool("dry-run", false, "with -set, do everything but actually set the clock")
+	debug             = flag.Bool("debug", false, "annotate the parsed date on standard error")
 	help              = flag.Bool("help", false, "display help information")
 	version           = flag.Bool("version", false, "output version information")
 )
 
+// getLocation returns UTC, or the time zone that TZ names.
+func getLocation() *time.Location {
+	if *printUTC {
+		return time.UTC
+	}
+	return parsedate.LocalZone()
+}
+
 // getTime returns the current time in either the default time zone or UTC.
 func getTime() time.Time {
-	if *printUTC {
-		return time.Now().UTC()
-	} else {
-		return time.Now()
+	return time.Now().In(getLocation())
+}
+
+// debugPrint prints a line of -debug output.
+func debugPrint(line string) {
+	fmt.Fprintf(os.Stderr, "date: %s\n", line)
+}
+
+// getDate returns the time described by the date string, relative to now.
+func getDate(date string) (time.Time, error) {
+	if !*debug {
+		return parsedate.Parse(date, getTime())
+	}
+
+	t, err := parsedate.ParseDebug(date, getTime(), debugPrint)
+	if err == nil {
+		debugPrint("timezone: " + parsedate.DescribeZone(getLocation()))
+		debugPrint(fmt.Sprintf("final: %d.%09d (epoch-seconds)", t.Unix(), t.Nanosecond()))
+		debugPrint(strftime("final: (Y-M-D) %Y-%m-%d %H:%M:%S (UTC)", t.UTC()))
+		debugPrint(strftime("final: (Y-M-D) %Y-%m-%d %H:%M:%S (UTC%:::z)", t))
 	}
+	return t, err
+}
+
+// setDate sets the clock to the time described by the date string, unless
+// this is a dry run, prints the time, and returns the exit status.
+func setDate(date string) int {
+	t, err := getDate(date)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "date: %s\n", err)
+		return 1
+	}
+
+	status := 0
+	if *dryRun {
+		if *debug {
+			debugPrint("dry run: not setting the clock")
+		}
+	} else if err := setClock(t); err != nil {
+		fmt.Fprintf(os.Stderr, "date: cannot set date: %s\n", err)
+		status = 1
+	}
+	printDate(t)
+	return status
+}
+
+// printDateFile prints the time described by each line of the file, or of
+// standard input if the file is "-", and returns the exit status.
+func printDateFile(name string) int {
+	var input io.Reader = os.Stdin
+	if name != "-" {
+		file, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "date: %s\n", err)
+			return 1
+		}
+		defer file.Close()
+		input = file
+	}
+
+	status := 0
+	scanner := bufio.NewScanner(input)
+	for scanner.Scan() {
+		t, err := getDate(scanner.Text())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "date: %s\n", err)
+			status = 1
+			continue
+		}
+		printDate(t)
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "date: %s: %s\n", name, err)
+		status = 1
+	}
+	return status
 }
 
 // getModificationTime returns the modification time of the file.
 func getModificationTime(file os.FileInfo) time.Time {
-	if *printUTC {
-		return file.ModTime().UTC()
-	} else {
-		return file.ModTime()
+	return file.ModTime().In(getLocation())
+}
+
+// getFormat returns the +FORMAT operand without its '+', or an empty string
+// if there is none.
+func getFormat() string {
+	for index := 0; index < flag.NArg(); index++ {
+		if strings.HasPrefix(flag.Arg(index), "+") {
+			return flag.Arg(index)[1:]
+		}
 	}
+	return ""
 }
 
 // printDate prints the time based on the layout format.
 func printDate(t time.Time) {
+	format := getFormat()
+	if *debug && (format != "" || !(*printRFC1123 || *printRFC3339 != "" || *printISO8601 != "")) {
+		layout := format
+		if layout == "" {
+			layout = "%a %b %e %H:%M:%S %Z %Y"
+		}
+		debugPrint(fmt.Sprintf("output format: '%s'", layout))
+	}
 	switch {
+	case format != "" && (*printRFC1123 || *printRFC3339 != "" || *printISO8601 != ""):
+		fmt.Println("date: multiple output formats specified")
+		os.Exit(1)
+	case format != "":
+		fmt.Println(strftime(format, t))
 	case *printRFC1123:
 		fmt.Println(t.Format(time.RFC1123Z))
 	case *printRFC3339 != "" && *printRFC3339 != "date" &&
@@ -124,19 +326,53 @@ func printDate(t time.Time) {
 	}
 }
 
+// getReferenceName returns the first operand that is not a +FORMAT.
+func getReferenceName() string {
+	for index := 0; index < flag.NArg(); index++ {
+		if !strings.HasPrefix(flag.Arg(index), "+") {
+			return flag.Arg(index)
+		}
+	}
+	return ""
+}
+
 // getReference creates an os.FileInfo of the reference file and returns it.
 func getReference() os.FileInfo {
-	file, err := os.Stat(flag.Arg(0))
+	file, err := os.Stat(getReferenceName())
 	if err != nil {
-		fmt.Printf("date: %s - No such file or directory\n", flag.Arg(0))
+		fmt.Printf("date: %s - No such file or directory\n", getReferenceName())
 		os.Exit(0)
 	}
 	return file
 }
 
 func main() {
+	specified := 0
+	for _, set := range []bool{*referenceMode, *dateString != "", *dateFile != ""} {
+		if set {
+			specified++
+		}
+	}
+
 	switch {
-	case *referenceMode && flag.NArg() < 1:
+	case *setString != "" && specified > 0:
+		fmt.Fprintln(os.Stderr, "date: the options to print and set the time may not be used together")
+		os.Exit(1)
+	case *setString != "":
+		os.Exit(setDate(*setString))
+	case specified > 1:
+		fmt.Fprintln(os.Stderr, "date: the options to specify dates for printing are mutually exclusive")
+		os.Exit(1)
+	case *dateFile != "":
+		os.Exit(printDateFile(*dateFile))
+	case *dateString != "":
+		t, err := getDate(*dateString)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "date: %s\n", err)
+			os.Exit(1)
+		}
+		printDate(t)
+	case *referenceMode && getReferenceName() == "":
 		fmt.Println("date: option requires an argument -- 'r'")
 	case *referenceMode:
 		printDate(getModificationTime(getReference()))
@@ -148,11 +384,11 @@ func main() {
 func init() {
 	flag.Parse()
 	if *help {
-		fmt.Println(HELP_TEXT)
+		os.Stdout.WriteString(HELP_TEXT) // The help text contains % sequences.
 		os.Exit(0)
 	}
 	if *version {
-		fmt.Println(VERSION_TEXT)
+		fmt.Print(VERSION_TEXT)
 		os.Exit(0)
 	}
 	if *printUTCLong || *printUTCLonger {
@@ -167,4 +403,13 @@ func init() {
 	if *referenceModeLong {
 		*referenceMode = true
 	}
+	if *dateStringLong != "" {
+		*dateString = *dateStringLong
+	}
+	if *dateFileLong != "" {
+		*dateFile = *dateFileLong
+	}
+	if *setStringLong != "" {
+		*setString = *setStringLong
+	}
 }
@@ -0,0 +1,26 @@
+//
+// settime.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Michael Murphy
+//
+
+// +build linux
+
+package main
+
+import "syscall"
+import "time"
+import "unsafe"
+
+const CLOCK_REALTIME = 0
+
+// setClock sets the system clock to t with clock_settime(2).
+func setClock(t time.Time) error {
+	ts := syscall.NsecToTimespec(t.UnixNano())
+	_, _, errno := syscall.Syscall(syscall.SYS_CLOCK_SETTIME, CLOCK_REALTIME, uintptr(unsafe.Pointer(&ts)), 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+//
+// settime_other.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Michael Murphy
+//
+
+// +build !linux
+
+package main
+
+import "errors"
+import "time"
+
+// setClock fails, as the clock can only be set on Linux.
+func setClock(t time.Time) error {
+	return errors.New("setting the clock is not supported on this system")
+}
@@ -0,0 +1,294 @@
+//
+// strftime.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Michael Murphy
+//
+package main
+
+import "bytes"
+import "fmt"
+import "strconv"
+import "strings"
+import "time"
+
+// The composite directives, expanded as they are in the C locale.
+var compositeDirectives = map[byte]string{
+	'c': "%a %b %e %H:%M:%S %Y",
+	'D': "%m/%d/%y",
+	'r': "%I:%M:%S %p",
+	'R': "%H:%M",
+	'T': "%H:%M:%S",
+	'x': "%m/%d/%y",
+	'X': "%H:%M:%S",
+}
+
+// A conversion is the text of a single directive along with how it is
+// padded: numbers are padded to width with pad, text is padded with spaces.
+type conversion struct {
+	text    string
+	numeric bool
+	width   int
+	pad     byte
+}
+
+// Returns a number padded to width with pad by default.
+func number(value, width int, pad byte) conversion {
+	return conversion{strconv.Itoa(value), true, width, pad}
+}
+
+// Returns a string that is not padded by default.
+func text(value string) conversion {
+	return conversion{value, false, 0, ' '}
+}
+
+// Returns the numeric time zone offset of t as +hh, +hh:mm or +hh:mm:ss,
+// with colons separating the fields when colons is at least 1. A colons value
+// of 3 selects the shortest form that shows the offset exactly.
+func zoneOffset(t time.Time, colons int) string {
+	_, offset := t.Zone()
+	sign := '+'
+	if offset < 0 {
+		sign = '-'
+		offset = -offset
+	}
+	hours, minutes, seconds := offset/3600, offset/60%60, offset%60
+
+	switch {
+	case colons == 0:
+		return fmt.Sprintf("%c%02d%02d", sign, hours, minutes)
+	case colons == 1, colons == 3 && seconds == 0 && minutes != 0:
+		return fmt.Sprintf("%c%02d:%02d", sign, hours, minutes)
+	case colons == 3 && seconds == 0:
+		return fmt.Sprintf("%c%02d", sign, hours)
+	}
+	return fmt.Sprintf("%c%02d:%02d:%02d", sign, hours, minutes, seconds)
+}
+
+// Returns the week of the year, counting weeks that start on firstDay, with
+// the days before the first such day in week 0.
+func weekOfYear(t time.Time, firstDay time.Weekday) int {
+	weekday := (int(t.Weekday()) - int(firstDay) + 7) % 7
+	return (t.YearDay() - 1 + 7 - weekday) / 7
+}
+
+// Returns the hour on a 12-hour clock.
+func twelveHour(t time.Time) int {
+	hour := t.Hour() % 12
+	if hour == 0 {
+		hour = 12
+	}
+	return hour
+}
+
+// Returns the floor of a / b, so that years before 0 get the right century.
+func floorDivide(a, b int) int {
+	if a < 0 && a%b != 0 {
+		return a/b - 1
+	}
+	return a / b
+}
+
+// convert returns the conversion for the directive verb, or false if the
+// directive is unknown. colons is the number of ':' before a 'z', and width
+// is the requested field width, which %N uses as its number of digits.
+func convert(t time.Time, verb byte, colons, width int) (conversion, bool) {
+	switch verb {
+	case 'a':
+		return text(t.Format("Mon")), true
+	case 'A':
+		return text(t.Weekday().String()), true
+	case 'b', 'h':
+		return text(t.Format("Jan")), true
+	case 'B':
+		return text(t.Month().String()), true
+	case 'C':
+		return number(floorDivide(t.Year(), 100), 2, '0'), true
+	case 'd':
+		return number(t.Day(), 2, '0'), true
+	case 'e':
+		return number(t.Day(), 2, ' '), true
+	case 'F':
+		return text(fmt.Sprintf("%04d-%02d-%02d", t.Year(), t.Month(), t.Day())), true
+	case 'g':
+		year, _ := t.ISOWeek()
+		return number((year%100+100)%100, 2, '0'), true
+	case 'G':
+		year, _ := t.ISOWeek()
+		return number(year, 0, '0'), true
+	case 'H':
+		return number(t.Hour(), 2, '0'), true
+	case 'I':
+		return number(twelveHour(t), 2, '0'), true
+	case 'j':
+		return number(t.YearDay(), 3, '0'), true
+	case 'k':
+		return number(t.Hour(), 2, ' '), true
+	case 'l':
+		return number(twelveHour(t), 2, ' '), true
+	case 'm':
+		return number(int(t.Month()), 2, '0'), true
+	case 'M':
+		return number(t.Minute(), 2, '0'), true
+	case 'n':
+		return text("\n"), true
+	case 'N':
+		digits := fmt.Sprintf("%09d", t.Nanosecond())
+		if width > 0 && width < 9 {
+			digits = digits[:width]
+		}
+		return text(digits), true
+	case 'p':
+		return text(t.Format("PM")), true
+	case 'P':
+		return text(strings.ToLower(t.Format("PM"))), true
+	case 'q':
+		return number((int(t.Month())-1)/3+1, 1, '0'), true
+	case 's':
+		return text(strconv.FormatInt(t.Unix(), 10)), true
+	case 'S':
+		return number(t.Second(), 2, '0'), true
+	case 't':
+		return text("\t"), true
+	case 'u':
+		return number((int(t.Weekday())+6)%7+1, 1, '0'), true
+	case 'U':
+		return number(weekOfYear(t, time.Sunday), 2, '0'), true
+	case 'V':
+		_, week := t.ISOWeek()
+		return number(week, 2, '0'), true
+	case 'w':
+		return number(int(t.Weekday()), 1, '0'), true
+	case 'W':
+		return number(weekOfYear(t, time.Monday), 2, '0'), true
+	case 'y':
+		return number((t.Year()%100+100)%100, 2, '0'), true
+	case 'Y':
+		return number(t.Year(), 0, '0'), true
+	case 'z':
+		return text(zoneOffset(t, colons)), true
+	case 'Z':
+		return text(t.Format("MST")), true
+	case '%':
+		return text("%"), true
+	}
+	if layout, ok := compositeDirectives[verb]; ok {
+		return text(strftime(layout, t)), true
+	}
+	return conversion{}, false
+}
+
+/* strftime formats t according to the GNU date FORMAT string. Every
+ * directive may carry flags, a field width and an ignored E or O modifier:
+ *
+ *   -  do not pad the field
+ *   _  pad the field with spaces
+ *   0  pad the field with zeros
+ *   ^  use upper case if possible
+ *   #  use the opposite case if possible
+ *
+ * Unknown directives are copied to the output unchanged. */
+func strftime(format string, t time.Time) string {
+	var buffer bytes.Buffer
+	for index := 0; index < len(format); index++ {
+		if format[index] != '%' {
+			buffer.WriteByte(format[index])
+			continue
+		}
+
+		start := index
+		index++
+		var pad byte
+		upper, swap := false, false
+		for ; index < len(format); index++ {
+			switch format[index] {
+			case '-', '_', '0':
+				pad = format[index]
+				continue
+			case '^':
+				upper = true
+				continue
+			case '#':
+				swap = true
+				continue
+			}
+			break
+		}
+		width := -1
+		if index < len(format) && format[index] >= '1' && format[index] <= '9' {
+			width = 0
+			for ; index < len(format) && format[index] >= '0' && format[index] <= '9'; index++ {
+				width = width*10 + int(format[index]-'0')
+			}
+		}
+		if index < len(format) && (format[index] == 'E' || format[index] == 'O') {
+			index++
+		}
+		colons := 0
+		for ; index < len(format) && format[index] == ':' && colons < 3; index++ {
+			colons++
+		}
+
+		if index == len(format) {
+			buffer.WriteString(format[start:])
+			break
+		}
+		c, ok := convert(t, format[index], colons, width)
+		if !ok || (colons > 0 && format[index] != 'z') {
+			buffer.WriteString(format[start : index+1])
+			continue
+		}
+		buffer.WriteString(applyFlags(c, format[index], pad, upper, swap, width))
+	}
+	return buffer.String()
+}
+
+// applyFlags pads and changes the case of a conversion as the flags and
+// width request.
+func applyFlags(c conversion, verb, pad byte, upper, swap bool, width int) string {
+	result := c.text
+	// Names are capitalised and %Z and %p are upper case already, so the
+	// opposite case is upper case for the former and lower case for the latter.
+	switch {
+	case upper:
+		result = strings.ToUpper(result)
+	case swap && (verb == 'Z' || verb == 'p'):
+		result = strings.ToLower(result)
+	case swap:
+		result = strings.ToUpper(result)
+	}
+
+	if verb == 'N' {
+		return result
+	}
+	if width < 0 {
+		width = c.width
+	}
+	switch pad {
+	case '-':
+		return result
+	case 0:
+		pad = c.pad
+	case '_':
+		pad = ' '
+	}
+
+	negative := c.numeric && strings.HasPrefix(result, "-")
+	if negative {
+		result = result[1:]
+		width--
+	}
+	if padding := width - len(result); padding > 0 {
+		if c.numeric && pad == '0' {
+			result = strings.Repeat("0", padding) + result
+		} else if c.numeric && negative {
+			return strings.Repeat(" ", padding) + "-" + result
+		} else {
+			result = strings.Repeat(string(pad), padding) + result
+		}
+	}
+	if negative {
+		result = "-" + result
+	}
+	return result
+}
@@ -4,350 +4,526 @@
 //
 // Written By: Michael Murphy
 //
-// TODO: Implement & and | expressions.
 package main
 
-import "flag"
+import "bytes"
 import "fmt"
-import "math"
+import "math/big"
 import "os"
-import "strconv"
+import "regexp"
 import "strings"
+import "unicode/utf8"
 
 const (
 	help_text = `
 	Usage: expr EXPRESSION
 	   or: expr OPTION
-	   
+
 	-help    display this help and exit
-	
+
 	-version output version information and exit
-	   
-	Print the value of EXPRESSION to standard output. EXPRESSIONS are listed below:
-	
-	<  - less than
-	
-	<= - less than or equal to
-	
-	=  - equal to
-	
-	=> - greater than or equal to
-	
-	>  - greater than
-	
-	+  - arithmetic sum of two arguments
-	
-	-  - arithmetic difference of two arguments
-	
-	*  - arithmetic product of two arguments
-	
-	/  - arithmetic quotient of two arguments
-	
-	%  - arithmetic remainder after dividing two arguments
-	
-	substr STRING STARTPOS LENGTH
-	      substring of STRING, STARTPOS counted from 1
-	      
-	index STRING CHAR
-	      index in STRING where any CHAR is found, or 0
-	      
-	length STRING
-	      length of STRING
+
+	Print the value of EXPRESSION to standard output. A blank line below
+	separates increasing precedence groups. EXPRESSION may be:
+
+	ARG1 | ARG2       ARG1 if it is neither null nor 0, otherwise ARG2
+
+	ARG1 & ARG2       ARG1 if neither argument is null or 0, otherwise 0
+
+	ARG1 < ARG2       ARG1 is less than ARG2
+	ARG1 <= ARG2      ARG1 is less than or equal to ARG2
+	ARG1 = ARG2       ARG1 is equal to ARG2
+	ARG1 != ARG2      ARG1 is unequal to ARG2
+	ARG1 >= ARG2      ARG1 is greater than or equal to ARG2
+	ARG1 > ARG2       ARG1 is greater than ARG2
+
+	ARG1 + ARG2       arithmetic sum of ARG1 and ARG2
+	ARG1 - ARG2       arithmetic difference of ARG1 and ARG2
+
+	ARG1 * ARG2       arithmetic product of ARG1 and ARG2
+	ARG1 / ARG2       arithmetic quotient of ARG1 divided by ARG2
+	ARG1 % ARG2       arithmetic remainder of ARG1 divided by ARG2
+
+	STRING : REGEXP   anchored pattern match of REGEXP in STRING
+
+	match STRING REGEXP        same as STRING : REGEXP
+	substr STRING POS LENGTH   substring of STRING, POS counted from 1
+	index STRING CHARS         index in STRING where any CHARS is found, or 0
+	length STRING              length of STRING
+	+ TOKEN                    interpret TOKEN as a string, even if it is a
+	                           keyword like 'match' or an operator like '/'
+
+	( EXPRESSION )             value of EXPRESSION
+
+	Beware that many operators need to be escaped or quoted for shells.
+	Comparisons are arithmetic if both ARGs are numbers, else lexicographical.
+	Pattern matches return the string matched between \( and \) or null; if
+	\( and \) are not used, they return the number of characters matched or 0.
+
+	Exit status is 0 if EXPRESSION is neither null nor 0, 1 if EXPRESSION is
+	null or 0, 2 if EXPRESSION is syntactically invalid, and 3 if an error
+	occurred.
 `
 	version_text = `
     expr (go-coreutils) 0.1
 
     Copyright (C) 2014, The GO-Coreutils Developers.
     This program comes with ABSOLUTELY NO WARRANTY; for details see
-    LICENSE. This is free software, and you are welcome to redistribute 
+    LICENSE. This is free software, and you are welcome to redistribute
     it under certain conditions in LICENSE.
 `
-)
 
-var (
-	help    = flag.Bool("help", false, "display help information")
-	version = flag.Bool("version", false, "display version information")
+	EXPR_TRUE    = 0 // The expression is neither null nor 0
+	EXPR_FALSE   = 1 // The expression is null or 0
+	EXPR_INVALID = 2 // The expression is syntactically invalid
+	EXPR_FAILURE = 3 // An error occurred while evaluating the expression
 )
 
-// Check initial state of flags.
-func processFlags() {
-	if *help {
-		fmt.Println(help_text)
-		os.Exit(0)
-	}
-	if *version {
-		fmt.Println(version_text)
-		os.Exit(0)
-	}
+// A value is the result of evaluating an expression: either an arbitrary
+// precision integer or a string.
+type value struct {
+	integer *big.Int
+	str     string
 }
 
-// Prints an error indicating that the syntax is wrong and exits.
-func printError() {
-	fmt.Println("expr: syntax error")
-	os.Exit(0)
+// Returns an integer value.
+func integerValue(integer *big.Int) value {
+	return value{integer: integer}
 }
 
-// Print an error and exit the program if there are no arguments.
-func checkIfNoArgumentsAreGiven() {
-	if flag.NArg() == 0 {
-		fmt.Println("expr: missing operand\nTry 'expr -help' for more information.")
-		os.Exit(0)
+// Returns an integer value of 1 for true and 0 for false.
+func booleanValue(boolean bool) value {
+	if boolean {
+		return integerValue(big.NewInt(1))
 	}
+	return integerValue(big.NewInt(0))
 }
 
-// Returns a slice of value arguments.
-func getValueSlice() []float64 {
-	// This will select every odd argument, which is a number.
-	valueSlice := make([]float64, 0)                   // Create a slice for storing values (numbers).
-	for index := 0; index <= flag.NArg(); index += 2 { // Loop through every odd argument
-		value, _ := strconv.ParseFloat(flag.Arg(index), 64) // Convert the flag argument, which is a string, into float32 with strconv.
-		valueSlice = append(valueSlice, value)              // Append value, which is currently a float64 value, as a float32 value to the value slice.
+// Returns a string value.
+func stringValue(str string) value {
+	return value{str: str}
+}
+
+// Returns the value as it is printed.
+func (v value) String() string {
+	if v.integer != nil {
+		return v.integer.String()
 	}
-	return valueSlice
+	return v.str
 }
 
-// Returns a slice of modifier arguments.
-func getModifierSlice() []string {
-	// This will select every even argument, which is a modifier (+.-,/,*)
-	modifierSlice := make([]string, 0)                // Create a slice for storing modifiers.
-	for index := 1; index < flag.NArg(); index += 2 { // Loop through every even argument
-		modifierSlice = append(modifierSlice, flag.Arg(index)) // Append the modifier to the modifier slice.
+// Returns true if the string is an optional minus sign followed by digits,
+// which is the only integer syntax that expr accepts.
+func looksLikeInteger(str string) bool {
+	str = strings.TrimPrefix(str, "-")
+	if str == "" {
+		return false
 	}
-	return modifierSlice
+	for index := 0; index < len(str); index++ {
+		if str[index] < '0' || str[index] > '9' {
+			return false
+		}
+	}
+	return true
 }
 
-// Return true if the current modifier is an inequality symbol.
-func isInequalitySymbol(test string) bool {
-	var isBool bool = false
-	switch test {
-	case "<", "<=", "=", "=>", ">", "!=":
-		isBool = true
+// Returns the value as an integer, if it is or looks like one.
+func (v value) toInteger() (*big.Int, bool) {
+	if v.integer != nil {
+		return v.integer, true
+	}
+	if !looksLikeInteger(v.str) {
+		return nil, false
 	}
-	return isBool
+	integer, ok := new(big.Int).SetString(v.str, 10)
+	return integer, ok
 }
 
-// Checks if the number can be an integer for calculating the modulus.
-func floatIsInteger(number float64) bool {
-	// If the float can be truncated into an int, return true.
-	if math.Trunc(number) == number {
+// Returns true if the value is null: the empty string or zero.
+func (v value) isNull() bool {
+	if v.integer != nil {
+		return v.integer.Sign() == 0
+	}
+	if v.str == "" {
 		return true
 	}
+	integer, ok := v.toInteger()
+	return ok && integer.Sign() == 0
+}
 
-	return false
+// Prints an error and exits with the given status.
+func printError(status int, format string, a ...interface{}) {
+	fmt.Fprintf(os.Stderr, "expr: "+format+"\n", a...)
+	os.Exit(status)
 }
 
-// Checks if the numbers can be modulated, and if so returns the modulated value.
-func calculateModulus(original, current float64) float64 {
-	var result float64
+/* A parser evaluates the expression made up by its tokens while it parses
+ * them, from the lowest precedence operator to the highest. Each function
+ * is told whether to evaluate: the operand of | or & that the other decides
+ * the result without is only parsed, so that its errors are not raised, as
+ * in 1 | 1 / 0. */
+type parser struct {
+	tokens   []string
+	position int
+}
 
-	// Check if the numbers can be modulated
-	if floatIsInteger(original) && floatIsInteger(current) {
-		result = float64(int64(original) % int64(current))
-	} else {
-		fmt.Println("expr: non-integer argument")
-		os.Exit(0)
+// Returns true if the next token is str, and consumes it if so.
+func (p *parser) accept(str string) bool {
+	if p.position < len(p.tokens) && p.tokens[p.position] == str {
+		p.position++
+		return true
 	}
+	return false
+}
 
-	return result
+// Returns the next token, or exits with a syntax error if there is none.
+func (p *parser) next() string {
+	if p.position == len(p.tokens) {
+		printError(EXPR_INVALID, "syntax error: missing argument after '%s'", p.tokens[p.position-1])
+	}
+	p.position++
+	return p.tokens[p.position-1]
 }
 
-// Return 1 if true, 0 if false.
-func booleanToFloat(boolean bool) float64 {
-	if boolean {
-		return 1
-	} else {
-		return 0
+// parseOr evaluates ARG1 | ARG2.
+func (p *parser) parseOr(evaluate bool) value {
+	left := p.parseAnd(evaluate)
+	for p.accept("|") {
+		right := p.parseAnd(evaluate && left.isNull())
+		if left.isNull() {
+			left = right
+			if right.isNull() {
+				left = integerValue(big.NewInt(0))
+			}
+		}
 	}
+	return left
 }
 
-// The initial result in an expression needs to be calculated differently than the rest.
-func calculateInitialResult(firstNum, secondNum float64, modifier string) float64 {
-	var result float64
-	switch modifier {
-	case "+":
-		result = firstNum + secondNum
-	case "-":
-		result = firstNum - secondNum
-	case "*":
-		result = firstNum * secondNum
-	case "/":
-		result = firstNum / secondNum
-	case "%":
-		result = calculateModulus(firstNum, secondNum)
-	default:
-		printError()
+// parseAnd evaluates ARG1 & ARG2.
+func (p *parser) parseAnd(evaluate bool) value {
+	left := p.parseComparison(evaluate)
+	for p.accept("&") {
+		right := p.parseComparison(evaluate && !left.isNull())
+		if left.isNull() || right.isNull() {
+			left = integerValue(big.NewInt(0))
+		}
 	}
-	return result
+	return left
 }
 
-// Calculate the range of numbers to calculate between expressions.
-func calculateExpressionRanges(valueSlice []float64, modifierSlice []string) ([]int, int) {
-	var currentRange, equalityCount int = 0, 0
-	expressionRanges := make([]int, 0)
-
-	// Loop through each modifier and check if it is an inequality symbol, then append the range.
-	for _, modifier := range modifierSlice {
-		if isInequalitySymbol(modifier) {
-			equalityCount++
-			expressionRanges = append(expressionRanges, currentRange+1)
-			currentRange = 0
-		} else {
-			currentRange++
+// compare returns the sign of left - right, comparing as integers if both
+// values look like integers, and as strings otherwise.
+func compare(left, right value) int {
+	if leftInteger, ok := left.toInteger(); ok {
+		if rightInteger, ok := right.toInteger(); ok {
+			return leftInteger.Cmp(rightInteger)
 		}
 	}
+	return strings.Compare(left.String(), right.String())
+}
 
-	// The above loop will exit before appending the last range, so this will append it.
-	currentRange++
-	expressionRanges = append(expressionRanges, currentRange)
+// parseComparison evaluates the comparison operators.
+func (p *parser) parseComparison(evaluate bool) value {
+	left := p.parseSum(evaluate)
+	for {
+		switch {
+		case p.accept("<"):
+			left = booleanValue(compare(left, p.parseSum(evaluate)) < 0)
+		case p.accept("<="):
+			left = booleanValue(compare(left, p.parseSum(evaluate)) <= 0)
+		case p.accept("="), p.accept("=="):
+			left = booleanValue(compare(left, p.parseSum(evaluate)) == 0)
+		case p.accept("!="):
+			left = booleanValue(compare(left, p.parseSum(evaluate)) != 0)
+		case p.accept(">="):
+			left = booleanValue(compare(left, p.parseSum(evaluate)) >= 0)
+		case p.accept(">"):
+			left = booleanValue(compare(left, p.parseSum(evaluate)) > 0)
+		default:
+			return left
+		}
+	}
+}
 
-	return expressionRanges, equalityCount
+// Returns both values as integers, or exits if either one is not.
+func integerOperands(left, right value) (*big.Int, *big.Int) {
+	leftInteger, leftOk := left.toInteger()
+	rightInteger, rightOk := right.toInteger()
+	if !leftOk || !rightOk {
+		printError(EXPR_INVALID, "non-integer argument")
+	}
+	return leftInteger, rightInteger
 }
 
-// Calculate each result between inequality expressions
-func calculateExpressions(valueSlice []float64, modifierSlice []string, expressionRanges []int) []float64 {
-	results := make([]float64, 0)
-	var result float64
-	var position int = 0
-
-	for _, currentRange := range expressionRanges {
-		if currentRange == 1 {
-			result = valueSlice[position]
-		} else if currentRange == 2 {
-			result = calculateInitialResult(valueSlice[position], valueSlice[position+1], modifierSlice[position])
-		} else if currentRange > 2 {
-			result = calculateInitialResult(valueSlice[position], valueSlice[position+1], modifierSlice[position])
-			for index := position; index < currentRange+position-2; index++ {
-				switch modifierSlice[index+1] {
-				case "+":
-					result += valueSlice[index+2]
-				case "-":
-					result -= valueSlice[index+2]
-				case "*":
-					result *= valueSlice[index+2]
-				case "/":
-					result /= valueSlice[index+2]
-				case "%":
-					result = calculateModulus(result, valueSlice[index+2])
-				default:
-					printError()
-				}
+// parseSum evaluates ARG1 + ARG2 and ARG1 - ARG2.
+func (p *parser) parseSum(evaluate bool) value {
+	left := p.parseProduct(evaluate)
+	for {
+		switch {
+		case p.accept("+"):
+			right := p.parseProduct(evaluate)
+			if evaluate {
+				a, b := integerOperands(left, right)
+				left = integerValue(new(big.Int).Add(a, b))
+			}
+		case p.accept("-"):
+			right := p.parseProduct(evaluate)
+			if evaluate {
+				a, b := integerOperands(left, right)
+				left = integerValue(new(big.Int).Sub(a, b))
 			}
+		default:
+			return left
 		}
-		results = append(results, result)
-		position = position + currentRange
 	}
-
-	return results
 }
 
-// Returns 1 for true, 0 for false, or any
-func calculateInequalities(results []float64, expressionRanges []int, boolCount int, modifierSlice []string) float64 {
-	currentValue := results[0]
-
-	for index := 0; index < boolCount; index++ {
-		currentModifier := modifierSlice[expressionRanges[index]-1]
-		nextValue := results[index+1]
-
-		switch currentModifier {
-		case "<":
-			currentValue = booleanToFloat(currentValue < nextValue)
-		case "<=":
-			currentValue = booleanToFloat(currentValue <= nextValue)
-		case "=":
-			currentValue = booleanToFloat(currentValue == nextValue)
-		case "=>":
-			currentValue = booleanToFloat(currentValue <= nextValue)
-		case ">":
-			currentValue = booleanToFloat(currentValue > nextValue)
-		case "!=":
-			currentValue = booleanToFloat(currentValue != nextValue)
+// parseProduct evaluates ARG1 * ARG2, ARG1 / ARG2 and ARG1 % ARG2. Division
+// truncates toward zero, as it does in C.
+func (p *parser) parseProduct(evaluate bool) value {
+	left := p.parseMatch(evaluate)
+	for {
+		switch {
+		case p.accept("*"):
+			right := p.parseMatch(evaluate)
+			if evaluate {
+				a, b := integerOperands(left, right)
+				left = integerValue(new(big.Int).Mul(a, b))
+			}
+		case p.accept("/"):
+			right := p.parseMatch(evaluate)
+			if evaluate {
+				a, b := integerOperands(left, right)
+				if b.Sign() == 0 {
+					printError(EXPR_INVALID, "division by zero")
+				}
+				left = integerValue(new(big.Int).Quo(a, b))
+			}
+		case p.accept("%"):
+			right := p.parseMatch(evaluate)
+			if evaluate {
+				a, b := integerOperands(left, right)
+				if b.Sign() == 0 {
+					printError(EXPR_INVALID, "division by zero")
+				}
+				left = integerValue(new(big.Int).Rem(a, b))
+			}
+		default:
+			return left
 		}
 	}
-
-	return currentValue
 }
 
-// Performs arithmetic calculations
-func calculateArithmetic(valueSlice []float64, modifierSlice []string) {
-	expressionRanges, boolCount := calculateExpressionRanges(valueSlice, modifierSlice)
+// parseMatch evaluates STRING : REGEXP.
+func (p *parser) parseMatch(evaluate bool) value {
+	left := p.parsePrimary(evaluate)
+	for p.accept(":") {
+		right := p.parsePrimary(evaluate)
+		if evaluate {
+			left = match(left.String(), right.String())
+		}
+	}
+	return left
+}
 
-	// Calculate the totals between expressions
-	results := calculateExpressions(valueSlice, modifierSlice, expressionRanges)
+// parsePrimary evaluates the keywords, '+ TOKEN', parenthesised expressions
+// and plain tokens.
+func (p *parser) parsePrimary(evaluate bool) value {
+	if p.position == len(p.tokens) {
+		if p.position == 0 {
+			printError(EXPR_INVALID, "syntax error: missing argument")
+		}
+		printError(EXPR_INVALID, "syntax error: missing argument after '%s'", p.tokens[p.position-1])
+	}
 
-	// Calculate inequality expressions between results or print result.
-	if boolCount != 0 {
-		fmt.Println(calculateInequalities(results, expressionRanges, boolCount, modifierSlice))
-	} else {
-		fmt.Println(results[0])
+	switch {
+	case p.accept("+"):
+		return stringValue(p.next())
+	case p.accept("length"):
+		return integerValue(big.NewInt(int64(utf8.RuneCountInString(p.parsePrimary(evaluate).String()))))
+	case p.accept("match"):
+		str := p.parsePrimary(evaluate).String()
+		pattern := p.parsePrimary(evaluate).String()
+		if !evaluate {
+			return integerValue(big.NewInt(0))
+		}
+		return match(str, pattern)
+	case p.accept("index"):
+		str := p.parsePrimary(evaluate).String()
+		return integerValue(big.NewInt(int64(index(str, p.parsePrimary(evaluate).String()))))
+	case p.accept("substr"):
+		str := p.parsePrimary(evaluate)
+		position := p.parsePrimary(evaluate)
+		length := p.parsePrimary(evaluate)
+		return stringValue(substr(str.String(), position, length))
+	case p.accept("("):
+		result := p.parseOr(evaluate)
+		if p.position == len(p.tokens) {
+			printError(EXPR_INVALID, "syntax error: expecting ')' after '%s'", p.tokens[p.position-1])
+		}
+		if !p.accept(")") {
+			printError(EXPR_INVALID, "syntax error: expecting ')' instead of '%s'", p.tokens[p.position])
+		}
+		return result
+	case p.accept(")"):
+		printError(EXPR_INVALID, "syntax error: unexpected ')'")
 	}
+	return stringValue(p.next())
 }
 
-// Returns the length of the string
-func getStringLength() int {
-	return len(flag.Arg(1))
+// Returns the 1-based character position of the first character of str that
+// appears in chars, or 0 if there is none.
+func index(str, chars string) int {
+	position := 0
+	for _, r := range str {
+		position++
+		if strings.ContainsRune(chars, r) {
+			return position
+		}
+	}
+	return 0
 }
 
-// Returns the index value of the position of the first occurence of a character.
-func getCharacterIndex() int {
-	return strings.IndexByte(flag.Arg(1), flag.Arg(2)[0]) + 1
-}
+// Returns the substring of str of length characters starting at the 1-based
+// character position, or the empty string if either is not positive.
+func substr(str string, position, length value) string {
+	start, startOk := position.toInteger()
+	count, countOk := length.toInteger()
+	runes := []rune(str)
+	if !startOk || !countOk || start.Sign() <= 0 || count.Sign() <= 0 ||
+		start.Cmp(big.NewInt(int64(len(runes)))) > 0 {
+		return ""
+	}
 
-// Returns a substring containing only the characters in the input range.
-func getSubstring() string {
-	inputString := flag.Arg(1)
-	start, starterr := strconv.Atoi(flag.Arg(2))
-	end, enderr := strconv.Atoi(flag.Arg(3))
+	first := int(start.Int64()) - 1
+	last := len(runes)
+	if count.Cmp(big.NewInt(int64(last-first))) < 0 {
+		last = first + int(count.Int64())
+	}
+	return string(runes[first:last])
+}
 
-	// Check for errors in syntax
-	if starterr != nil || enderr != nil {
-		printError()
+// translateRegexp converts a POSIX basic regular expression into the
+// POSIX extended syntax understood by regexp.CompilePOSIX, and reports
+// whether it contains a \( \) group.
+func translateRegexp(pattern string) (string, bool) {
+	var buffer bytes.Buffer
+	hasGroup := false
+	// A '*' is literal at the start of the expression or of a group.
+	atStart := true
+
+	for index := 0; index < len(pattern); index++ {
+		c := pattern[index]
+		switch {
+		case c == '\\' && index+1 < len(pattern):
+			index++
+			switch e := pattern[index]; e {
+			case '(':
+				hasGroup = true
+				buffer.WriteByte('(')
+				atStart = true
+				continue
+			case ')', '{', '}', '|', '+', '?':
+				buffer.WriteByte(e)
+			case '1', '2', '3', '4', '5', '6', '7', '8', '9':
+				printError(EXPR_FAILURE, "back references are not supported: '\\%c'", e)
+			default:
+				buffer.WriteString(regexp.QuoteMeta(string(e)))
+			}
+		case c == '[':
+			// Copy the bracket expression, where backslash is literal.
+			end := index + 1
+			if end < len(pattern) && pattern[end] == '^' {
+				end++
+			}
+			if end < len(pattern) && pattern[end] == ']' {
+				end++
+			}
+			for end < len(pattern) && pattern[end] != ']' {
+				if pattern[end] == '[' && end+1 < len(pattern) &&
+					(pattern[end+1] == ':' || pattern[end+1] == '.' || pattern[end+1] == '=') {
+					if close := strings.Index(pattern[end+2:], string(pattern[end+1])+"]"); close >= 0 {
+						end += close + 3
+					}
+				}
+				end++
+			}
+			if end >= len(pattern) {
+				printError(EXPR_FAILURE, "Unmatched [, [^, [:, [., or [=")
+			}
+			buffer.WriteString(strings.Replace(pattern[index:end+1], `\`, `\\`, -1))
+			index = end
+		case c == '*' && atStart:
+			buffer.WriteString(`\*`)
+		case c == '^' && index == 0:
+			// The pattern is anchored already.
+		case c == '$' && index == len(pattern)-1:
+			buffer.WriteByte('$')
+		case c == '.' || c == '*':
+			buffer.WriteByte(c)
+		default:
+			buffer.WriteString(regexp.QuoteMeta(string(c)))
+		}
+		atStart = false
 	}
+	return buffer.String(), hasGroup
+}
 
-	// In case the user sets a length beyond the slice size,
-	// set end to the length of the input string.
-	if end > len(inputString) {
-		end = len(inputString)
+// match evaluates STRING : REGEXP. The pattern is anchored at the start of
+// the string and returns the first \( \) group if there is one, or the
+// number of characters matched otherwise.
+func match(str, pattern string) value {
+	translated, hasGroup := translateRegexp(pattern)
+	re, err := regexp.CompilePOSIX("^(" + translated + ")")
+	if err != nil {
+		printError(EXPR_FAILURE, "invalid regular expression: %s", pattern)
 	}
 
-	return inputString[start-1 : end]
+	matches := re.FindStringSubmatch(str)
+	if hasGroup {
+		if matches == nil {
+			return stringValue("")
+		}
+		return stringValue(matches[2])
+	}
+	if matches == nil {
+		return integerValue(big.NewInt(0))
+	}
+	return integerValue(big.NewInt(int64(utf8.RuneCountInString(matches[0]))))
 }
 
 func main() {
-	flag.Parse()
-	processFlags()
-
-	// If there are no arguments, print an error and exit.
-	checkIfNoArgumentsAreGiven()
-
-	// Check if length is the first argument
-	switch flag.Arg(0) {
-	case "match":
-		//TODO
-		fmt.Println("not implemented")
-		os.Exit(0)
-	case "substr":
-		fmt.Println(getSubstring())
-		os.Exit(0)
-	case "index":
-		fmt.Println(getCharacterIndex())
-		os.Exit(0)
-	case "length":
-		fmt.Println(getStringLength())
-		os.Exit(0)
-	case "+":
-		//TODO
-		fmt.Println("not implemented")
+	// The flag package cannot be used here, because operands such as '-1'
+	// would be taken for flags.
+	args := os.Args[1:]
+	if len(args) > 0 {
+		switch args[0] {
+		case "-help", "--help":
+			fmt.Print(help_text)
+			os.Exit(0)
+		case "-version", "--version":
+			fmt.Print(version_text)
+			os.Exit(0)
+		case "--":
+			args = args[1:]
+		}
+	}
+
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "expr: missing operand\nTry 'expr -help' for more information.")
+		os.Exit(EXPR_INVALID)
 	}
 
-	// Obtain value and modifier slices
-	valueSlice := getValueSlice()
-	modifierSlice := getModifierSlice()
+	p := &parser{tokens: args}
+	result := p.parseOr(true)
+	if p.position != len(p.tokens) {
+		printError(EXPR_INVALID, "syntax error: unexpected argument '%s'", p.tokens[p.position])
+	}
 
-	// Determine how to process the arguments
-	switch modifierSlice[0] {
-	case "+", "-", "*", "/", "%", "<", "<=", "=", "=>", ">", "!=":
-		calculateArithmetic(valueSlice, modifierSlice)
-	default:
-		printError()
+	fmt.Println(result)
+	if result.isNull() {
+		os.Exit(EXPR_FALSE)
 	}
+	os.Exit(EXPR_TRUE)
 }
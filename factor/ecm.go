@@ -0,0 +1,184 @@
+//
+// ecm.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Michael Murphy
+//
+package main
+
+import "math/big"
+import "math/rand"
+
+const (
+	ECM_B1     = 2000 // The stage one bound of the first curves
+	ECM_B2     = 50   // The stage two bound, as a multiple of the stage one bound
+	ECM_D      = 210  // Half of the giant step size used in stage two
+	ECM_CURVES = 25   // The number of curves to try before raising the bounds
+	ECM_SEED   = 1    // Seeds the curve selection, so output is reproducible
+)
+
+// A point on a Montgomery curve in projective X:Z coordinates.
+type point struct {
+	x, z *big.Int
+}
+
+// A curve carries the modulus and (A + 2) / 4 of the Montgomery curve
+// By^2 = x^3 + Ax^2 + x that the arithmetic below is done on.
+type curve struct {
+	n, a24 *big.Int
+}
+
+// Returns (a * b) mod n in a freshly allocated integer.
+func (c *curve) mul(a, b *big.Int) *big.Int {
+	product := new(big.Int).Mul(a, b)
+	return product.Mod(product, c.n)
+}
+
+// double returns 2P.
+func (c *curve) double(p point) point {
+	sum := new(big.Int).Add(p.x, p.z)
+	difference := new(big.Int).Sub(p.x, p.z)
+	sumSquared := c.mul(sum, sum)
+	differenceSquared := c.mul(difference, difference)
+	t := new(big.Int).Sub(sumSquared, differenceSquared)
+	z := new(big.Int).Add(differenceSquared, c.mul(c.a24, t))
+	return point{c.mul(sumSquared, differenceSquared), c.mul(t, z)}
+}
+
+// add returns P + Q, given the difference P - Q.
+func (c *curve) add(p, q, difference point) point {
+	u := c.mul(new(big.Int).Sub(p.x, p.z), new(big.Int).Add(q.x, q.z))
+	v := c.mul(new(big.Int).Add(p.x, p.z), new(big.Int).Sub(q.x, q.z))
+	sum := new(big.Int).Add(u, v)
+	diff := new(big.Int).Sub(u, v)
+	return point{c.mul(difference.z, c.mul(sum, sum)), c.mul(difference.x, c.mul(diff, diff))}
+}
+
+// multiply returns kP with the Montgomery ladder.
+func (c *curve) multiply(p point, k int64) point {
+	r0, r1 := p, c.double(p)
+	for bit := big.NewInt(k).BitLen() - 2; bit >= 0; bit-- {
+		if k&(1<<uint(bit)) != 0 {
+			r0, r1 = c.add(r1, r0, p), c.double(r1)
+		} else {
+			r0, r1 = c.double(r0), c.add(r1, r0, p)
+		}
+	}
+	return r0
+}
+
+/* suyamaCurve builds a random curve and a point on it with Suyama's
+ * parametrisation, which guarantees a group order divisible by 12. If the
+ * setup itself stumbles on a divisor of n, that divisor is returned instead. */
+func suyamaCurve(n *big.Int, random *rand.Rand) (*curve, point, *big.Int) {
+	sigma := new(big.Int).Rand(random, new(big.Int).Sub(n, big.NewInt(7)))
+	sigma.Add(sigma, big.NewInt(6))
+
+	c := &curve{n: n}
+	u := c.mul(sigma, sigma)
+	u.Sub(u, big.NewInt(5))
+	v := c.mul(sigma, big.NewInt(4))
+	u3 := c.mul(c.mul(u, u), u)
+	v3 := c.mul(c.mul(v, v), v)
+
+	// a24 = (v - u)^3 (3u + v) / (16 u^3 v)
+	vu := new(big.Int).Sub(v, u)
+	numerator := c.mul(c.mul(c.mul(vu, vu), vu), new(big.Int).Add(new(big.Int).Mul(u, big.NewInt(3)), v))
+	denominator := c.mul(c.mul(u3, v), big.NewInt(16))
+	inverse := new(big.Int).ModInverse(denominator, n)
+	if inverse == nil {
+		g := new(big.Int).GCD(nil, nil, denominator, n)
+		if g.Cmp(big.NewInt(1)) > 0 && g.Cmp(n) < 0 {
+			return nil, point{}, g
+		}
+		return nil, point{}, nil
+	}
+	c.a24 = c.mul(numerator, inverse)
+	return c, point{u3, v3}, nil
+}
+
+/* stageTwo extends the search to curves whose order has a single prime
+ * factor q between the two bounds, given Q, the point left by stage one. It
+ * uses the baby-step giant-step continuation: with baby steps jQ for odd
+ * j < ECM_D and giant steps R = 2k*ECM_D*Q, every such q is 2k*ECM_D +- j
+ * for some pair, and qQ is the point at infinity modulo p exactly when the x
+ * coordinates of R and jQ agree modulo p. The differences of the x
+ * coordinates are multiplied together, and the gcd of the product with n is
+ * returned. */
+func (c *curve) stageTwo(q point, primes []int64, low, high int64) *big.Int {
+	baby := make([]point, ECM_D)
+	baby[1] = q
+	step := c.double(q)
+	baby[3] = c.add(step, q, q)
+	for j := 5; j < ECM_D; j += 2 {
+		baby[j] = c.add(baby[j-2], step, baby[j-4])
+	}
+
+	giant := c.multiply(q, 2*ECM_D)
+	k := low / (2 * ECM_D)
+	previous := c.multiply(giant, k-1)
+	current := c.multiply(giant, k)
+
+	product := big.NewInt(1)
+	index := 0
+	for index < len(primes) && primes[index] <= low {
+		index++
+	}
+	for ; index < len(primes) && primes[index] <= high; index++ {
+		for primes[index] > 2*ECM_D*k+ECM_D {
+			previous, current = current, c.add(current, giant, previous)
+			k++
+		}
+		j := primes[index] - 2*ECM_D*k
+		if j < 0 {
+			j = -j
+		}
+		// j is odd and below ECM_D, since q is an odd prime.
+		difference := new(big.Int).Sub(c.mul(current.x, baby[j].z), c.mul(baby[j].x, current.z))
+		product = c.mul(product, difference)
+	}
+	return new(big.Int).GCD(nil, nil, product, c.n)
+}
+
+/* ellipticCurveMethod finds a divisor of the composite n with stage one of
+ * Lenstra's elliptic curve method: a point is multiplied by every prime power
+ * up to the bound, and if the order of the curve modulo some prime divisor p
+ * is smooth over that bound, the result is the point at infinity modulo p,
+ * so that p divides its Z coordinate. */
+func ellipticCurveMethod(n *big.Int) *big.Int {
+	random := rand.New(rand.NewSource(ECM_SEED))
+	for bound := int64(ECM_B1); ; bound *= 2 {
+		primes := sievePrimes(int(bound*ECM_B2) + 1)
+		for attempt := 0; attempt < ECM_CURVES; attempt++ {
+			c, p, divisor := suyamaCurve(n, random)
+			if divisor != nil {
+				return divisor
+			}
+			if c == nil {
+				continue
+			}
+
+			for _, prime := range primes {
+				if prime > bound {
+					break
+				}
+				power := prime
+				for power <= bound/prime {
+					power *= prime
+				}
+				p = c.multiply(p, power)
+			}
+
+			g := new(big.Int).GCD(nil, nil, p.z, n)
+			if g.Cmp(big.NewInt(1)) > 0 && g.Cmp(n) < 0 {
+				return g
+			}
+			if g.Cmp(big.NewInt(1)) == 0 {
+				g = c.stageTwo(p, primes, bound, bound*ECM_B2)
+				if g.Cmp(big.NewInt(1)) > 0 && g.Cmp(n) < 0 {
+					return g
+				}
+			}
+		}
+	}
+}
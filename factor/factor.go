@@ -6,21 +6,30 @@
 //
 package main
 
+import "bufio"
 import "bytes"
 import "flag"
 import "fmt"
-import "strconv"
+import "math/big"
+import "io"
 import "os"
+import "runtime"
+import "sort"
+import "strings"
+import "sync"
 
 const (
 	help_text = `
-    Usage: factor [NUMBER]...
-    
+    Usage: factor [OPTION] [NUMBER]...
+
     Print the prime factors of each specified integer number. If none are
-    specified on the command line, read them from standard output.
-    
+    specified on the command line, read them from standard input.
+
+    -h, -exponents
+          print repeated factors in form p^e unless e is 1
+
     -help display this help and exit
-    
+
     -version output version information and exit
 `
 	version_text = `
@@ -28,94 +37,229 @@ const (
 
     Copyright (C) 2014, The GO-Coreutils Developers.
     This program comes with ABSOLUTELY NO WARRANTY; for deprintTails see
-    LICENSE. This is free software, and you are welcome to redistribute 
+    LICENSE. This is free software, and you are welcome to redistribute
     it under certain conditions in LICENSE.
 `
 )
 
+const WINDOW = 64 // Numbers in flight per worker, bounding the reordering buffer
+
 var (
-	help    = flag.Bool("help", false, "display help information")
-	version = flag.Bool("version", false, "display version information")
+	exponents     = flag.Bool("h", false, "print repeated factors in form p^e unless e is 1")
+	exponentsLong = flag.Bool("exponents", false, "print repeated factors in form p^e unless e is 1")
+	help          = flag.Bool("help", false, "display help information")
+	version       = flag.Bool("version", false, "display version information")
 )
 
-type factorList []int
+type factorList []*big.Int
 
-// toString returns the factorList as a string.
+// toString returns the factorList as a string, collapsing repeated factors
+// into p^e when exponents are enabled.
 func (numbers *factorList) toString() string {
 	var buffer bytes.Buffer
-	for _, number := range *numbers {
-		buffer.WriteString(" " + strconv.Itoa(number))
+	list := *numbers
+	for index := 0; index < len(list); index++ {
+		buffer.WriteString(" " + list[index].String())
+		if !*exponents {
+			continue
+		}
+		power := 1
+		for index+1 < len(list) && list[index+1].Cmp(list[index]) == 0 {
+			power++
+			index++
+		}
+		if power > 1 {
+			fmt.Fprintf(&buffer, "^%d", power)
+		}
 	}
 	return buffer.String()
 }
 
 /* getFactorList generates a factorList type containing all of the prime factors
- * of the 'number' input. The prime seive used will first check if the number is
- * divisible by the index, and if true, appends the index to the factor list. If
- * the 'index' value is greater than the square root of the 'number', the value
- * of 'number' is also a prime factor. If the square root of 'number' is equal
- * to 'index', then we can add 'index' twice and exit. If the value of 'index'
- * is two, change it to one so that we will only start checking for odd numbers
- * as prime candidates. */
-func getFactorList(number int) factorList {
+ * of the 'number' input, in ascending order. Small factors are removed by
+ * trial division, and whatever is left over is split with Pollard's rho
+ * (falling back to the elliptic curve method for large composites) until
+ * only primes remain. */
+func getFactorList(number *big.Int) factorList {
 	var factors factorList
-	for index := 2; index <= number; index += 2 {
-		if number%index == 0 {
-			factors = append(factors, index)
-			number /= index
-			index = 0
-		} else if index*index > number {
-			factors = append(factors, number)
-			break
-		} else if index*index == number {
-			factors = append(factors, index)
-			factors = append(factors, index)
-			break
-		}
-		if index == 2 {
-			index = 1
-		}
+	if number.Cmp(big.NewInt(2)) < 0 {
+		return factors
 	}
+
+	rest := new(big.Int).Set(number)
+	factors = trialDivision(rest, factors)
+	factors = splitFactors(rest, factors)
+	sort.Sort(factors)
 	return factors
 }
 
+// splitFactors appends the prime factors of the composite or prime number to
+// the factors.
+func splitFactors(number *big.Int, factors factorList) factorList {
+	if number.Cmp(big.NewInt(1)) == 0 {
+		return factors
+	}
+	if isPrime(number) {
+		return append(factors, number)
+	}
+
+	divisor := findDivisor(number)
+	quotient := new(big.Int).Quo(number, divisor)
+	factors = splitFactors(divisor, factors)
+	return splitFactors(quotient, factors)
+}
+
+// Implements sort.Interface so that factors print in ascending order.
+func (numbers factorList) Len() int           { return len(numbers) }
+func (numbers factorList) Less(i, j int) bool { return numbers[i].Cmp(numbers[j]) < 0 }
+func (numbers factorList) Swap(i, j int)      { numbers[i], numbers[j] = numbers[j], numbers[i] }
+
 /* getNumber parses the input number in string format and returns the value
- * as a number if it really is a number -- else returns an error. */
-func getNumber(currentNumber string) int {
-	number, err := strconv.Atoi(currentNumber)
-	if err != nil {
-		fmt.Printf("factor: '%s' is not a valid positive integer\n",
-			flag.Arg(0))
-		os.Exit(0)
+ * as a number if it really is a number -- else returns false. */
+func getNumber(currentNumber string) (*big.Int, bool) {
+	number, ok := new(big.Int).SetString(strings.TrimPrefix(currentNumber, "+"), 10)
+	if !ok || number.Sign() < 0 || strings.HasPrefix(currentNumber, "+-") {
+		return nil, false
 	}
-	return number
+	return number, true
 }
 
-func main() {
-	if flag.NArg() == 0 {
-		var number int
-		for {
-			fmt.Scan(&number)
-			factors := getFactorList(number)
-			fmt.Print(number, ":", factors.toString(), "\n")
+// A job is one number to factor, tagged with its position in the input.
+type job struct {
+	index  int
+	number string
+}
+
+// A result is the output line for a job, or the error message if the number
+// was invalid.
+type result struct {
+	index  int
+	output string
+	err    string
+}
+
+// factorJob factors the number of a job and formats its output line.
+func factorJob(j job) result {
+	number, ok := getNumber(j.number)
+	if !ok {
+		return result{index: j.index, err: fmt.Sprintf("factor: '%s' is not a valid positive integer", j.number)}
+	}
+	factors := getFactorList(number)
+	return result{index: j.index, output: number.String() + ":" + factors.toString() + "\n"}
+}
+
+/* factorAll factors the numbers on a pool of workers and prints the results
+ * in input order, returning the exit status. A result that arrives early is
+ * held until those before it are printed; at most WINDOW numbers per worker
+ * are in flight, so a slow number cannot make the buffer grow without bound.
+ * Output is flushed whenever no further results are ready, so interactive
+ * use still sees each line as soon as it is factored. */
+func factorAll(numbers <-chan string) int {
+	workers := runtime.GOMAXPROCS(0)
+	jobs := make(chan job)
+	results := make(chan result, workers)
+	window := make(chan bool, workers*WINDOW)
+
+	go func() {
+		index := 0
+		for number := range numbers {
+			window <- true
+			jobs <- job{index, number}
+			index++
 		}
-	} else {
-		for index := 0; index < flag.NArg(); index++ {
-			number := getNumber(flag.Arg(index))
-			factors := getFactorList(number)
-			fmt.Print(number, ":", factors.toString(), "\n")
+		close(jobs)
+	}()
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < workers; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				results <- factorJob(j)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	status := 0
+	output := bufio.NewWriter(os.Stdout)
+	pending := make(map[int]result)
+	next := 0
+	for r := range results {
+		pending[r.index] = r
+		for r, ok := pending[next]; ok; r, ok = pending[next] {
+			if r.err != "" {
+				output.Flush()
+				fmt.Fprintln(os.Stderr, r.err)
+				status = 1
+			} else {
+				output.WriteString(r.output)
+			}
+			delete(pending, next)
+			next++
+			<-window
+		}
+		if len(results) == 0 {
+			output.Flush()
 		}
 	}
+	output.Flush()
+	return status
 }
 
-func init() {
+// readNumbers sends every whitespace separated number of the input, line by
+// line until EOF, and returns the error that stopped it, if any.
+func readNumbers(input io.Reader, numbers chan<- string) error {
+	scanner := bufio.NewScanner(input)
+	for scanner.Scan() {
+		for _, field := range strings.Fields(scanner.Text()) {
+			numbers <- field
+		}
+	}
+	return scanner.Err()
+}
+
+func main() {
+	processFlags()
+
+	var readError error
+	numbers := make(chan string)
+	go func() {
+		if flag.NArg() == 0 {
+			readError = readNumbers(os.Stdin, numbers)
+		} else {
+			for index := 0; index < flag.NArg(); index++ {
+				numbers <- flag.Arg(index)
+			}
+		}
+		close(numbers)
+	}()
+
+	status := factorAll(numbers)
+	if readError != nil {
+		fmt.Fprintf(os.Stderr, "factor: %s\n", readError)
+		status = 1
+	}
+	os.Exit(status)
+}
+
+// processFlags parses the command line, handling -help and -version, and
+// -exponents as the long form of -h.
+func processFlags() {
 	flag.Parse()
 	if *help {
-		fmt.Println(help_text)
+		fmt.Print(help_text)
 		os.Exit(0)
 	}
 	if *version {
-		fmt.Println(version_text)
+		fmt.Print(version_text)
 		os.Exit(0)
 	}
+	if *exponentsLong {
+		*exponents = true
+	}
 }
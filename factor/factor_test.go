@@ -0,0 +1,93 @@
+//
+// factor_test.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Michael Murphy
+//
+package main
+
+import "io/ioutil"
+import "math/big"
+import "os"
+import "strings"
+import "testing"
+
+// Returns the numbers of the corpus of semiprimes, which range from ten
+// digits to twenty-two.
+func readCorpus(t testing.TB) []string {
+	data, err := ioutil.ReadFile("testdata/semiprimes.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Fields(string(data))
+}
+
+func TestGetFactorList(t *testing.T) {
+	numbers := append(readCorpus(t), "0", "1", "2", "4", "1024", "9999999967", "18446744073709551615")
+	for _, text := range numbers {
+		number, ok := getNumber(text)
+		if !ok {
+			t.Fatalf("'%s' is not a valid positive integer", text)
+		}
+		factors := getFactorList(number)
+		product := big.NewInt(1)
+		for index, factor := range factors {
+			if !isPrime(factor) {
+				t.Errorf("%s: factor %s is not prime", text, factor)
+			}
+			if index > 0 && factor.Cmp(factors[index-1]) < 0 {
+				t.Errorf("%s: factors out of order:%s", text, factors.toString())
+			}
+			product.Mul(product, factor)
+		}
+		if number.Cmp(big.NewInt(2)) < 0 {
+			if len(factors) != 0 {
+				t.Errorf("%s: has factors:%s", text, factors.toString())
+			}
+		} else if product.Cmp(number) != 0 {
+			t.Errorf("%s: factors%s multiply to %s", text, factors.toString(), product)
+		}
+	}
+}
+
+func BenchmarkGetFactorList(b *testing.B) {
+	var numbers []*big.Int
+	for _, text := range readCorpus(b) {
+		number, _ := getNumber(text)
+		numbers = append(numbers, number)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, number := range numbers {
+			getFactorList(number)
+		}
+	}
+}
+
+// BenchmarkFactorAll factors the corpus on the worker pool, with the output
+// thrown away.
+func BenchmarkFactorAll(b *testing.B) {
+	corpus := readCorpus(b)
+	null, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer null.Close()
+	stdout := os.Stdout
+	os.Stdout = null
+	defer func() { os.Stdout = stdout }()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		numbers := make(chan string)
+		go func() {
+			for _, number := range corpus {
+				numbers <- number
+			}
+			close(numbers)
+		}()
+		if status := factorAll(numbers); status != 0 {
+			b.Fatalf("factorAll returned %d", status)
+		}
+	}
+}
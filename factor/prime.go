@@ -0,0 +1,123 @@
+//
+// prime.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Michael Murphy
+//
+package main
+
+import "math/big"
+
+// The bound of the small primes used for trial division.
+const SIEVE_LIMIT = 1 << 12
+
+// The small primes, in ascending order, found by the sieve of Eratosthenes.
+// They are computed once and shared read-only by every worker.
+var smallPrimes = sievePrimes(SIEVE_LIMIT)
+var smallPrimeInts = bigPrimes(smallPrimes)
+
+// The Miller-Rabin bases that are deterministic for every number below
+// 3317044064679887385961981, which covers all 64-bit inputs with room to spare.
+var millerRabinBases = []int64{2, 3, 5, 7, 11, 13, 17, 19, 23, 29, 31, 37, 41}
+
+// The largest number for which millerRabinBases is known to be deterministic.
+var millerRabinLimit, _ = new(big.Int).SetString("3317044064679887385961981", 10)
+
+// sievePrimes returns every prime below limit.
+func sievePrimes(limit int) []int64 {
+	composite := make([]bool, limit)
+	primes := make([]int64, 0)
+	for number := 2; number < limit; number++ {
+		if composite[number] {
+			continue
+		}
+		primes = append(primes, int64(number))
+		for multiple := number * number; multiple < limit; multiple += number {
+			composite[multiple] = true
+		}
+	}
+	return primes
+}
+
+// bigPrimes converts the primes to big integers.
+func bigPrimes(primes []int64) []*big.Int {
+	ints := make([]*big.Int, len(primes))
+	for index, prime := range primes {
+		ints[index] = big.NewInt(prime)
+	}
+	return ints
+}
+
+// trialDivision divides every small prime out of number, appending each one
+// to the factors as it goes.
+func trialDivision(number *big.Int, factors factorList) factorList {
+	quotient, remainder := new(big.Int), new(big.Int)
+	square := new(big.Int)
+	for _, divisor := range smallPrimeInts {
+		if square.Mul(divisor, divisor).Cmp(number) > 0 {
+			break
+		}
+		for {
+			quotient.QuoRem(number, divisor, remainder)
+			if remainder.Sign() != 0 {
+				break
+			}
+			factors = append(factors, divisor)
+			number.Set(quotient)
+		}
+	}
+
+	// Whatever is left is prime if it is below the square of the sieve bound.
+	if number.Cmp(big.NewInt(1)) > 0 && number.Cmp(big.NewInt(SIEVE_LIMIT*SIEVE_LIMIT)) < 0 {
+		factors = append(factors, new(big.Int).Set(number))
+		number.SetInt64(1)
+	}
+	return factors
+}
+
+// millerRabin returns false if base proves that the odd number n is
+// composite, given n - 1 = d * 2^s.
+func millerRabin(n, nMinusOne, d *big.Int, s int, base *big.Int) bool {
+	x := new(big.Int).Exp(base, d, n)
+	if x.Cmp(big.NewInt(1)) == 0 || x.Cmp(nMinusOne) == 0 {
+		return true
+	}
+	for round := 1; round < s; round++ {
+		x.Mul(x, x).Mod(x, n)
+		if x.Cmp(nMinusOne) == 0 {
+			return true
+		}
+		if x.Cmp(big.NewInt(1)) == 0 {
+			return false
+		}
+	}
+	return false
+}
+
+// isPrime tests the number for primality with the Miller-Rabin test. The
+// result is exact below millerRabinLimit; above it, further random bases
+// and a Lucas test are added by big.Int.ProbablyPrime.
+func isPrime(n *big.Int) bool {
+	if n.Cmp(big.NewInt(2)) < 0 {
+		return false
+	}
+	remainder := new(big.Int)
+	for _, p := range smallPrimeInts[:25] { // The primes below 100
+		if n.Cmp(p) == 0 {
+			return true
+		}
+		if remainder.Mod(n, p).Sign() == 0 {
+			return false
+		}
+	}
+
+	nMinusOne := new(big.Int).Sub(n, big.NewInt(1))
+	s := int(nMinusOne.TrailingZeroBits())
+	d := new(big.Int).Rsh(nMinusOne, uint(s))
+	for _, base := range millerRabinBases {
+		if !millerRabin(n, nMinusOne, d, s, big.NewInt(base)) {
+			return false
+		}
+	}
+	return n.Cmp(millerRabinLimit) < 0 || n.ProbablyPrime(20)
+}
@@ -0,0 +1,88 @@
+//
+// rho.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Michael Murphy
+//
+package main
+
+import "math/big"
+
+const (
+	RHO_BATCH = 128 // Differences multiplied together between gcds
+
+	// Inputs of at least ECM_BITS bits give Pollard's rho RHO_LIMIT
+	// iterations per polynomial before moving on to the elliptic curve method.
+	ECM_BITS  = 128
+	RHO_LIMIT = 1 << 16
+)
+
+// findDivisor returns a non-trivial divisor of the composite number n.
+func findDivisor(n *big.Int) *big.Int {
+	if n.Bit(0) == 0 {
+		return big.NewInt(2)
+	}
+	if root := new(big.Int).Sqrt(n); new(big.Int).Mul(root, root).Cmp(n) == 0 {
+		return root
+	}
+
+	limit := 0
+	if n.BitLen() >= ECM_BITS {
+		limit = RHO_LIMIT
+	}
+	for c := int64(1); ; c++ {
+		if divisor := pollardBrent(n, big.NewInt(c), limit); divisor != nil {
+			return divisor
+		}
+		if limit != 0 {
+			return ellipticCurveMethod(n)
+		}
+	}
+}
+
+/* pollardBrent looks for a divisor of n with Brent's variant of Pollard's
+ * rho algorithm, iterating x^2 + c. Differences are multiplied together in
+ * batches so that only one gcd is needed per RHO_BATCH steps, backtracking
+ * one step at a time if a batch overshoots to n. It returns nil if no
+ * divisor is found for this c, or within limit iterations when limit is
+ * non-zero. */
+func pollardBrent(n, c *big.Int, limit int) *big.Int {
+	f := func(x *big.Int) {
+		x.Mul(x, x).Add(x, c).Mod(x, n)
+	}
+	one := big.NewInt(1)
+	y, x, ys := big.NewInt(2), new(big.Int), new(big.Int)
+	q, g, diff := big.NewInt(1), big.NewInt(1), new(big.Int)
+
+	for r := 1; g.Cmp(one) == 0; r *= 2 {
+		if limit != 0 && r > limit {
+			return nil
+		}
+		x.Set(y)
+		for i := 0; i < r; i++ {
+			f(y)
+		}
+		for k := 0; k < r && g.Cmp(one) == 0; k += RHO_BATCH {
+			ys.Set(y)
+			for i := 0; i < RHO_BATCH && i < r-k; i++ {
+				f(y)
+				q.Mul(q, diff.Sub(x, y).Abs(diff)).Mod(q, n)
+			}
+			g.GCD(nil, nil, q, n)
+		}
+	}
+
+	if g.Cmp(n) == 0 {
+		for {
+			f(ys)
+			g.GCD(nil, nil, diff.Sub(x, ys).Abs(diff), n)
+			if g.Cmp(one) != 0 {
+				break
+			}
+		}
+	}
+	if g.Cmp(n) == 0 {
+		return nil
+	}
+	return g
+}
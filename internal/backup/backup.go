@@ -0,0 +1,131 @@
+//
+// backup.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Michael Murphy
+//
+
+// Package backup names the backups that mv and cp make of the files they
+// replace, following the GNU --backup=CONTROL and --suffix=SUFFIX options.
+package backup
+
+import "fmt"
+import "os"
+import "path/filepath"
+import "strconv"
+import "strings"
+
+// A Control is a method of naming backups.
+type Control int
+
+const (
+	NONE     Control = iota // Make no backups
+	SIMPLE                  // Append the suffix to the name
+	NUMBERED                // Append .~N~, one more than the highest N so far
+	EXISTING                // Numbered if numbered backups exist, else simple
+)
+
+// The names of each control, in the order GNU lists them.
+var controls = []struct {
+	names   []string
+	control Control
+}{
+	{[]string{"none", "off"}, NONE},
+	{[]string{"simple", "never"}, SIMPLE},
+	{[]string{"existing", "nil"}, EXISTING},
+	{[]string{"numbered", "t"}, NUMBERED},
+}
+
+// ValidArguments lists the names that ParseControl accepts, as GNU prints
+// them after an invalid argument.
+const ValidArguments = `Valid arguments are:
+  - 'none', 'off'
+  - 'simple', 'never'
+  - 'existing', 'nil'
+  - 'numbered', 't'`
+
+/* ParseControl returns the control that the name, or an unambiguous prefix
+ * of it, stands for. An empty name is the value of VERSION_CONTROL, or
+ * EXISTING if that is not set either. */
+func ParseControl(name string) (Control, error) {
+	if name == "" {
+		name = os.Getenv("VERSION_CONTROL")
+		if name == "" {
+			return EXISTING, nil
+		}
+	}
+
+	found := -1
+	for index, entry := range controls {
+		for _, candidate := range entry.names {
+			if candidate == name {
+				return entry.control, nil
+			}
+			if strings.HasPrefix(candidate, name) {
+				if found >= 0 && found != index {
+					return NONE, fmt.Errorf("ambiguous argument '%s'", name)
+				}
+				found = index
+			}
+		}
+	}
+	if found < 0 {
+		return NONE, fmt.Errorf("invalid argument '%s'", name)
+	}
+	return controls[found].control, nil
+}
+
+// Suffix returns the suffix of simple backups: the given one, else the value
+// of SIMPLE_BACKUP_SUFFIX, else "~". A suffix holding a '/' is ignored.
+func Suffix(suffix string) string {
+	for _, candidate := range []string{suffix, os.Getenv("SIMPLE_BACKUP_SUFFIX")} {
+		if candidate != "" && !strings.Contains(candidate, "/") {
+			return candidate
+		}
+	}
+	return "~"
+}
+
+// Returns the highest N of the numbered backups, name.~N~, of the file, or
+// 0 if there are none.
+func highestNumber(name string) int {
+	directory, err := os.Open(filepath.Dir(name))
+	if err != nil {
+		return 0
+	}
+	defer directory.Close()
+	entries, err := directory.Readdirnames(-1)
+	if err != nil {
+		return 0
+	}
+
+	prefix := filepath.Base(name) + ".~"
+	highest := 0
+	for _, entry := range entries {
+		// name.~ itself has both the prefix and the suffix, in one ~.
+		if len(entry) <= len(prefix)+1 || !strings.HasPrefix(entry, prefix) || !strings.HasSuffix(entry, "~") {
+			continue
+		}
+		digits := entry[len(prefix) : len(entry)-1]
+		if number, err := strconv.Atoi(digits); err == nil && number > highest && digits[0] != '0' {
+			highest = number
+		}
+	}
+	return highest
+}
+
+// Name returns the name to give the backup of the file, or an empty string
+// if the control is NONE.
+func Name(name string, control Control, suffix string) string {
+	switch control {
+	case NONE:
+		return ""
+	case SIMPLE:
+		return name + suffix
+	}
+	highest := highestNumber(name)
+	if control == EXISTING && highest == 0 {
+		return name + suffix
+	}
+	return fmt.Sprintf("%s.~%d~", name, highest+1)
+}
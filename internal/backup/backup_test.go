@@ -0,0 +1,51 @@
+//
+// backup_test.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Michael Murphy
+//
+
+package backup
+
+import "io/ioutil"
+import "os"
+import "path/filepath"
+import "testing"
+
+func TestName(t *testing.T) {
+	directory, err := ioutil.TempDir("", "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+	name := filepath.Join(directory, "b")
+
+	// Names that look almost like numbered backups are not taken for them.
+	for _, entry := range []string{"b", "b.~", "b.~~", "b.~0~", "b.~01~", "b.~x~", "c.~7~"} {
+		if err := ioutil.WriteFile(filepath.Join(directory, entry), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, test := range []struct {
+		control Control
+		want    string
+	}{
+		{NONE, ""},
+		{SIMPLE, name + "~"},
+		{EXISTING, name + "~"},
+		{NUMBERED, name + ".~1~"},
+	} {
+		if got := Name(name, test.control, "~"); got != test.want {
+			t.Errorf("Name(%d) = %q, want %q", test.control, got, test.want)
+		}
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(directory, "b.~9~"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, control := range []Control{EXISTING, NUMBERED} {
+		if got, want := Name(name, control, "~"), name+".~10~"; got != want {
+			t.Errorf("Name(%d) = %q, want %q", control, got, want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+//
+// cli.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Abram C. Isola, Michael Murphy
+//
+
+// Package cli holds the command line handling and the messages that cp, mv
+// and rm share: flags with optional values, the order flags were given in,
+// usage errors, and errors worded as the C library words them.
+package cli
+
+import "fmt"
+import "os"
+import "path/filepath"
+import "strings"
+
+// An OptionalValue is a flag whose value may be left out, as in -backup and
+// -backup=numbered; Given records whether the flag was given at all.
+type OptionalValue struct {
+	Given bool
+	Value string
+}
+
+func (o *OptionalValue) String() string   { return o.Value }
+func (o *OptionalValue) IsBoolFlag() bool { return true }
+
+func (o *OptionalValue) Set(value string) error {
+	o.Given = true
+	if value == "true" { // The flag was given without a value
+		value = ""
+	}
+	o.Value = value
+	return nil
+}
+
+// A Flag is a flag as given on the command line: its name without dashes,
+// and its value, if it was given one after = or, for a flag that takes
+// one, as the following argument.
+type Flag struct {
+	Name     string
+	Value    string
+	HasValue bool
+}
+
+/* GivenFlags returns the flags among the arguments, in the order they were
+ * given. The flag package does not keep the order, which decides which of
+ * several conflicting flags takes effect. The flags named in valueFlags take
+ * their value from the following argument unless it is given after =. */
+func GivenFlags(arguments []string, valueFlags map[string]bool) []Flag {
+	var flags []Flag
+	for index := 0; index < len(arguments); index++ {
+		argument := arguments[index]
+		if argument == "--" || !strings.HasPrefix(argument, "-") || argument == "-" {
+			break
+		}
+		flag := Flag{Name: strings.TrimLeft(argument, "-")}
+		if equals := strings.Index(flag.Name, "="); equals >= 0 {
+			flag.Name, flag.Value, flag.HasValue = flag.Name[:equals], flag.Name[equals+1:], true
+		} else if valueFlags[flag.Name] && index+1 < len(arguments) {
+			index++
+			flag.Value, flag.HasValue = arguments[index], true
+		}
+		flags = append(flags, flag)
+	}
+	return flags
+}
+
+// UsageError prints the program's error with a pointer to its help, and
+// exits.
+func UsageError(program, format string, a ...interface{}) {
+	fmt.Fprintf(os.Stderr, program+": "+format+"\n", a...)
+	fmt.Fprintf(os.Stderr, "Try '%s -help' for more information.\n", program)
+	os.Exit(1)
+}
+
+// Returns the error's underlying message, capitalised as the C library's
+// messages are.
+func ErrorString(err error) string {
+	switch e := err.(type) {
+	case *os.PathError:
+		err = e.Err
+	case *os.LinkError:
+		err = e.Err
+	case *os.SyscallError:
+		err = e.Err
+	}
+	message := err.Error()
+	if message == "" {
+		return message
+	}
+	return strings.ToUpper(message[:1]) + message[1:]
+}
+
+// Returns true if the path lies inside the directory.
+func IsInside(path, directory string) bool {
+	absolutePath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	absoluteDirectory, err := filepath.Abs(directory)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(absolutePath, strings.TrimSuffix(absoluteDirectory, "/")+"/")
+}
+
+// Returns true if the name is a directory, following symbolic links.
+func IsDirectory(name string) bool {
+	info, err := os.Stat(name)
+	return err == nil && info.IsDir()
+}
+
+// Returns true if standard input is a terminal.
+func IsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
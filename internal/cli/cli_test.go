@@ -0,0 +1,30 @@
+//
+// cli_test.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Michael Murphy
+//
+
+package cli
+
+import "reflect"
+import "testing"
+
+func TestGivenFlags(t *testing.T) {
+	valueFlags := map[string]bool{"S": true, "jobs": true}
+	for _, test := range []struct {
+		arguments []string
+		want      []Flag
+	}{
+		{[]string{"-i", "--force", "a"}, []Flag{{"i", "", false}, {"force", "", false}}},
+		{[]string{"-S", "-n", "-i"}, []Flag{{"S", "-n", true}, {"i", "", false}}},
+		{[]string{"--jobs=2", "-interactive=once"}, []Flag{{"jobs", "2", true}, {"interactive", "once", true}}},
+		{[]string{"-f", "--", "-i"}, []Flag{{"f", "", false}}},
+		{[]string{"-", "-i"}, nil},
+		{[]string{"-jobs"}, []Flag{{"jobs", "", false}}},
+	} {
+		if got := GivenFlags(test.arguments, valueFlags); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("GivenFlags(%q) = %v, want %v", test.arguments, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,12 @@
+//
+// copy_file_range_arm.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Michael Murphy
+//
+
+// +build linux
+
+package filecopy
+
+const SYS_COPY_FILE_RANGE = 391 // copy_file_range(2) system call number
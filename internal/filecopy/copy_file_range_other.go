@@ -0,0 +1,12 @@
+//
+// copy_file_range_other.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Michael Murphy
+//
+
+// +build linux,!amd64,!386,!arm,!arm64
+
+package filecopy
+
+const SYS_COPY_FILE_RANGE = -1 // copy_file_range(2) is not wired up here
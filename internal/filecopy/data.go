@@ -0,0 +1,191 @@
+//
+// data.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Michael Murphy
+//
+
+// +build linux
+
+package filecopy
+
+import "io"
+import "os"
+import "syscall"
+import "unsafe"
+
+// The whence values of lseek(2) that find the next data and the next hole
+// of a file.
+const (
+	SEEK_DATA = 3
+	SEEK_HOLE = 4
+)
+
+const MAX_CHUNK = 1 << 30 // Bytes asked of the kernel in one call
+
+// How a range of data is copied, fastest first. A method that the kernel or
+// the file systems turn out not to support is given up for the next one.
+const (
+	USE_COPY_FILE_RANGE = iota // In the kernel, which may share or offload the blocks
+	USE_SENDFILE               // In the kernel, through the page cache
+	USE_READ_WRITE             // Through a buffer of our own
+)
+
+// copyFileRange copies up to length bytes at the offset of in to the same
+// offset of out, and returns how many it copied.
+func copyFileRange(out, in *os.File, offset int64, length int) (int, error) {
+	number := SYS_COPY_FILE_RANGE
+	if number < 0 {
+		return 0, syscall.ENOSYS
+	}
+	inOffset, outOffset := offset, offset
+	n, _, errno := syscall.Syscall6(uintptr(number), in.Fd(), uintptr(unsafe.Pointer(&inOffset)),
+		out.Fd(), uintptr(unsafe.Pointer(&outOffset)), uintptr(length), 0)
+	if errno != 0 {
+		return 0, errno
+	}
+	return int(n), nil
+}
+
+// Returns true if the error means the method cannot copy between the files
+// at all, rather than that the copy failed.
+func unsupported(err error) bool {
+	switch err {
+	case syscall.ENOSYS, syscall.EXDEV, syscall.EINVAL, syscall.EOPNOTSUPP, syscall.EBADF,
+		syscall.EPERM, syscall.ETXTBSY:
+		return true
+	}
+	return false
+}
+
+/* copyRange copies the bytes from offset to end of in to the same place in
+ * out, with the first of the methods, from *method on, that works. It stops
+ * early if in turns out to be shorter. */
+func copyRange(out, in *os.File, offset, end int64, method *int) error {
+	var buffer []byte
+	for offset < end {
+		length := end - offset
+		if length > MAX_CHUNK {
+			length = MAX_CHUNK
+		}
+
+		var n int
+		var err error
+		switch *method {
+		case USE_COPY_FILE_RANGE:
+			n, err = copyFileRange(out, in, offset, int(length))
+		case USE_SENDFILE:
+			if _, err = out.Seek(offset, io.SeekStart); err == nil {
+				inOffset := offset
+				n, err = syscall.Sendfile(int(out.Fd()), int(in.Fd()), &inOffset, int(length))
+			}
+		default:
+			if buffer == nil {
+				buffer = make([]byte, BUFFER_SIZE)
+			}
+			if length > BUFFER_SIZE {
+				length = BUFFER_SIZE
+			}
+			n, err = in.ReadAt(buffer[:length], offset)
+			if err == io.EOF {
+				err = nil
+			}
+			if err != nil {
+				return err
+			}
+			if _, err := out.WriteAt(buffer[:n], offset); err != nil {
+				return err
+			}
+		}
+
+		if err != nil && *method < USE_READ_WRITE && unsupported(err) {
+			*method++
+			continue
+		}
+		if err != nil {
+			return &os.PathError{Op: "write", Path: out.Name(), Err: err}
+		}
+		if n == 0 {
+			return nil // The file is shorter than it was.
+		}
+		offset += int64(n)
+	}
+	return nil
+}
+
+/* copyExtents copies the data of in up to the size, leaving its holes as
+ * holes in out. The holes are found with SEEK_DATA and SEEK_HOLE, and it
+ * returns false if the file system cannot find them. */
+func copyExtents(out, in *os.File, size int64, method *int) (bool, error) {
+	for offset := int64(0); offset < size; {
+		data, err := in.Seek(offset, SEEK_DATA)
+		if err != nil && underlying(err) == syscall.ENXIO {
+			return true, nil // The rest is a hole.
+		}
+		if err != nil && offset == 0 {
+			return false, nil
+		}
+		if err != nil {
+			return true, err
+		}
+		if data >= size {
+			return true, nil
+		}
+		hole, err := in.Seek(data, SEEK_HOLE)
+		if err != nil || hole > size {
+			hole = size
+		}
+		if err := copyRange(out, in, data, hole, method); err != nil {
+			return true, err
+		}
+		offset = hole
+	}
+	return true, nil
+}
+
+/* copyData copies the data of in, a regular file with the status, to out.
+ * The kernel copies it where it can: with copy_file_range, which lets file
+ * systems share or offload the blocks, and else with sendfile. Holes in
+ * the source are kept by copying only the data between them, unless the
+ * copier says otherwise. With SPARSE_ALWAYS every block of zeros becomes a
+ * hole, which only reading the file finds, and with SPARSE_NEVER nothing
+ * does, so neither lets the kernel decide. */
+func (c *Copier) copyData(out, in *os.File, stat *syscall.Stat_t) error {
+	blockSize := int(stat.Blksize)
+	if blockSize <= 0 || blockSize > BUFFER_SIZE {
+		blockSize = 4096
+	}
+	method := USE_COPY_FILE_RANGE
+	size := stat.Size
+
+	var err error
+	switch {
+	case c.Sparse == SPARSE_ALWAYS:
+		size, err = copyBlocks(out, in, 0, true, blockSize)
+	case c.Sparse == SPARSE_NEVER:
+		method = USE_SENDFILE
+		err = copyRange(out, in, 0, size, &method)
+	case stat.Blocks*512 < size:
+		var found bool
+		if found, err = copyExtents(out, in, size, &method); err == nil && !found {
+			size, err = copyBlocks(out, in, 0, true, blockSize)
+		}
+	default:
+		err = copyRange(out, in, 0, size, &method)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Whatever has been added since the file was looked at is copied too.
+	end, err := copyBlocks(out, in, size, c.Sparse == SPARSE_ALWAYS, blockSize)
+	if err != nil {
+		return err
+	}
+	if end < size {
+		end = size
+	}
+
+	// A hole at the end leaves the file short until it is extended.
+	return out.Truncate(end)
+}
@@ -0,0 +1,31 @@
+//
+// data_other.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Michael Murphy
+//
+
+// +build !linux
+
+package filecopy
+
+import "os"
+import "syscall"
+
+/* copyData copies the data of in, a regular file with the status, to out,
+ * through a buffer of our own. Without SEEK_DATA and SEEK_HOLE the holes of
+ * the source are kept, unless the copier says otherwise, by leaving a hole
+ * for every block of zeros of a file that takes less room than its size. */
+func (c *Copier) copyData(out, in *os.File, stat *syscall.Stat_t) error {
+	blockSize := int(stat.Blksize)
+	if blockSize <= 0 || blockSize > BUFFER_SIZE {
+		blockSize = 4096
+	}
+	sparse := c.Sparse == SPARSE_ALWAYS || (c.Sparse == SPARSE_AUTO && stat.Blocks*512 < stat.Size)
+	end, err := copyBlocks(out, in, 0, sparse, blockSize)
+	if err != nil {
+		return err
+	}
+	// A hole at the end leaves the file short until it is extended.
+	return out.Truncate(end)
+}
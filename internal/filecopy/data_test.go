@@ -0,0 +1,143 @@
+//
+// data_test.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Michael Murphy
+//
+
+// +build linux
+
+package filecopy
+
+import "bytes"
+import "io/ioutil"
+import "os"
+import "os/exec"
+import "path/filepath"
+import "strings"
+import "syscall"
+import "testing"
+
+/* mountImage mounts a new file system of the type, which is tmpfs or one
+ * made on a loopback image, and returns where, unmounting it when the test
+ * ends. The test is skipped unless it runs as root with loop devices. */
+func mountImage(t *testing.T, fsType string) string {
+	if os.Getuid() != 0 {
+		t.Skip("mounting file systems needs root")
+	}
+	temporary, err := ioutil.TempDir("", "filecopy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(temporary) })
+	mountPoint := filepath.Join(temporary, "mnt")
+	if err := os.Mkdir(mountPoint, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	source, options := fsType, "size=64m"
+	if fsType != "tmpfs" {
+		image := filepath.Join(temporary, "image")
+		if err := ioutil.WriteFile(image, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Truncate(image, 64<<20); err != nil {
+			t.Fatal(err)
+		}
+		if output, err := exec.Command("mkfs."+fsType, "-q", "-F", image).CombinedOutput(); err != nil {
+			t.Skipf("cannot make %s: %v: %s", fsType, err, output)
+		}
+		output, err := exec.Command("losetup", "--find", "--show", image).Output()
+		if err != nil {
+			t.Skipf("no loop device: %v", err)
+		}
+		source, options = strings.TrimSpace(string(output)), ""
+		t.Cleanup(func() { exec.Command("losetup", "--detach", source).Run() })
+	}
+	if err := syscall.Mount(source, mountPoint, fsType, 0, options); err != nil {
+		t.Skipf("cannot mount %s: %v", fsType, err)
+	}
+	t.Cleanup(func() { syscall.Unmount(mountPoint, 0) })
+	return mountPoint
+}
+
+const SPARSE_SIZE = 1 << 20 // The size of the file with holes
+
+/* makeSparse makes a file of SPARSE_SIZE with 64 KiB of data at its start
+ * and in its middle, 64 KiB of zeros written out before the middle, and
+ * holes everywhere else, including its end. */
+func makeSparse(t *testing.T, name string) []byte {
+	content := make([]byte, SPARSE_SIZE)
+	for index := 0; index < 64<<10; index++ {
+		content[index] = byte(index%251 + 1)
+		content[SPARSE_SIZE/2+index] = byte(index%241 + 1)
+	}
+	file, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	for _, offset := range []int{0, SPARSE_SIZE / 4, SPARSE_SIZE / 2} {
+		if _, err := file.WriteAt(content[offset:offset+64<<10], int64(offset)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := file.Truncate(SPARSE_SIZE); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
+
+// Returns the bytes the file takes on disk.
+func allocated(t *testing.T, name string) int64 {
+	var stat syscall.Stat_t
+	if err := syscall.Stat(name, &stat); err != nil {
+		t.Fatal(err)
+	}
+	return stat.Blocks * 512
+}
+
+func TestCopySparse(t *testing.T) {
+	for _, fsType := range []string{"tmpfs", "ext4"} {
+		t.Run(fsType, func(t *testing.T) {
+			directory := mountImage(t, fsType)
+			source := filepath.Join(directory, "sparse")
+			content := makeSparse(t, source)
+			sourceAllocated := allocated(t, source)
+			if sourceAllocated >= SPARSE_SIZE {
+				t.Skipf("%s does not make holes", fsType)
+			}
+
+			for _, test := range []struct {
+				name   string
+				sparse int
+				check  func(allocated int64) bool
+			}{
+				// The holes of the source are kept, and the zeros written out.
+				{"auto", SPARSE_AUTO, func(allocated int64) bool { return allocated == sourceAllocated }},
+				// The zeros written out become holes as well.
+				{"always", SPARSE_ALWAYS, func(allocated int64) bool { return allocated < sourceAllocated }},
+				// Every block is written.
+				{"never", SPARSE_NEVER, func(allocated int64) bool { return allocated >= SPARSE_SIZE }},
+			} {
+				destination := filepath.Join(directory, test.name)
+				if err := New(Options{Sparse: test.sparse}).Copy(source, destination); err != nil {
+					t.Fatalf("%s: %v", test.name, err)
+				}
+				copied, err := ioutil.ReadFile(destination)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(copied, content) {
+					t.Errorf("%s: the copy differs from the source", test.name)
+				}
+				if size := allocated(t, destination); !test.check(size) {
+					t.Errorf("%s: the copy takes %d bytes on disk, and the source %d", test.name, size, sourceAllocated)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,351 @@
+//
+// filecopy.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Michael Murphy
+//
+
+// Package filecopy copies files and directory trees along with their
+// metadata. It is the copy engine of cp, and of mv when it moves files
+// between file systems.
+package filecopy
+
+import "fmt"
+import "math/rand"
+import "os"
+import "path/filepath"
+import "syscall"
+import "time"
+
+// Which symbolic links a Copier follows.
+const (
+	DEREF_NEVER        = iota // Copy symbolic links as links
+	DEREF_COMMAND_LINE        // Follow the links given to Copy, but none inside directories
+	DEREF_ALWAYS              // Follow every link
+)
+
+// When a Copier clones the data of regular files rather than copying it.
+const (
+	REFLINK_AUTO   = iota // Clone where the file system can, else copy
+	REFLINK_ALWAYS        // Clone, failing where the file system cannot
+	REFLINK_NEVER         // Always copy
+)
+
+// When a Copier leaves holes in the copies of regular files.
+const (
+	SPARSE_AUTO   = iota // Where the source has holes
+	SPARSE_ALWAYS        // For every block of zeros
+	SPARSE_NEVER         // Never, writing every block
+)
+
+// Options select what a Copier copies and which metadata it preserves.
+type Options struct {
+	Recursive    bool // Copy the contents of directories
+	Mode         bool // Preserve the permission bits, including setuid and sticky
+	Ownership    bool // Preserve the owner and group, where permitted
+	Timestamps   bool // Preserve the access and modification times
+	Links        bool // Preserve hard links between the files copied
+	Xattr        bool // Preserve extended attributes
+	Dereference  int  // Which symbolic links to follow, DEREF_NEVER by default
+	HardLink     bool // Hard link files instead of copying them
+	SymbolicLink bool // Make symbolic links to files instead of copying them
+	Reflink      int  // When to clone data, REFLINK_AUTO by default
+	Sparse       int  // When to make holes, SPARSE_AUTO by default
+	Force        bool // Remove a destination that cannot be opened and try again
+
+	// Exists, if set, is called for each destination that already exists
+	// and is not a directory being copied into, and returns whether to
+	// replace it. It may move the destination aside, as a backup. Without
+	// it, an existing destination is an error.
+	Exists func(source, destination string, sourceStat, destinationStat *syscall.Stat_t) (bool, error)
+
+	// Copied, if set, is called for each file once it has been made.
+	Copied func(source, destination string)
+
+	// Errors, if set, is given each error met inside a directory, and the
+	// rest of the directory is still copied. Without it, the first error
+	// stops the copy.
+	Errors func(err error)
+}
+
+// All preserves everything that can be preserved, as mv does.
+var All = Options{Recursive: true, Mode: true, Ownership: true, Timestamps: true, Links: true, Xattr: true}
+
+// A fileID identifies a file by its device and inode number.
+type fileID struct {
+	dev, ino uint64
+}
+
+// A Copier copies files, remembering those with several hard links so that
+// the copies are linked in the same way.
+type Copier struct {
+	Options
+	links map[fileID]string
+}
+
+// New returns a Copier with the options.
+func New(options Options) *Copier {
+	return &Copier{Options: options, links: make(map[fileID]string)}
+}
+
+// stat gets the status of the source, following it if it is a symbolic
+// link that the options say to follow.
+func (c *Copier) stat(source string, commandLine bool, stat *syscall.Stat_t) error {
+	if c.Dereference == DEREF_ALWAYS || (c.Dereference == DEREF_COMMAND_LINE && commandLine) {
+		if err := syscall.Stat(source, stat); err != nil {
+			return &os.PathError{Op: "stat", Path: source, Err: err}
+		}
+		return nil
+	}
+	if err := syscall.Lstat(source, stat); err != nil {
+		return &os.PathError{Op: "lstat", Path: source, Err: err}
+	}
+	return nil
+}
+
+/* Copy copies the source to the destination. The source is followed if it
+ * is a symbolic link only as the options say, and directories are copied
+ * with their contents if the copier is recursive. A directory is copied
+ * into an existing directory, and an existing file is replaced only if the
+ * Exists function says so. */
+func (c *Copier) Copy(source, destination string) error {
+	return c.copy(source, destination, true)
+}
+
+func (c *Copier) copy(source, destination string, commandLine bool) error {
+	var stat syscall.Stat_t
+	if err := c.stat(source, commandLine, &stat); err != nil {
+		return err
+	}
+	directory := stat.Mode&syscall.S_IFMT == syscall.S_IFDIR
+
+	var existing syscall.Stat_t
+	exists := syscall.Lstat(destination, &existing) == nil
+	existingDirectory := exists && existing.Mode&syscall.S_IFMT == syscall.S_IFDIR
+	switch {
+	case directory && exists && !existingDirectory:
+		return fmt.Errorf("cannot overwrite non-directory '%s' with directory '%s'", destination, source)
+	case !directory && existingDirectory:
+		return fmt.Errorf("cannot overwrite directory '%s' with non-directory", destination)
+	case exists && !directory:
+		if c.Exists == nil {
+			return &os.PathError{Op: "create", Path: destination, Err: syscall.EEXIST}
+		}
+		replace, err := c.Exists(source, destination, &stat, &existing)
+		if err != nil || !replace {
+			return err
+		}
+		exists = syscall.Lstat(destination, &existing) == nil
+	}
+
+	if c.Links && !directory && stat.Nlink > 1 {
+		id := fileID{uint64(stat.Dev), uint64(stat.Ino)}
+		if target, ok := c.links[id]; ok {
+			err := c.replace(exists, destination, func() error { return os.Link(target, destination) })
+			if err == nil && c.Copied != nil {
+				c.Copied(source, destination)
+			}
+			return err
+		}
+		c.links[id] = destination
+	}
+
+	var err error
+	created := !exists
+	switch {
+	case directory:
+		return c.copyDirectory(source, destination, &stat, existingDirectory)
+	case c.HardLink:
+		// A link is made in place of a file only if the copier is forced.
+		err = c.replace(exists && c.Force, destination, func() error { return os.Link(source, destination) })
+	case c.SymbolicLink:
+		err = c.replace(exists && c.Force, destination, func() error { return os.Symlink(source, destination) })
+	case stat.Mode&syscall.S_IFMT == syscall.S_IFREG:
+		created, err = c.copyRegular(source, destination, &stat, exists)
+	case stat.Mode&syscall.S_IFMT == syscall.S_IFLNK:
+		err = c.replace(exists, destination, func() error { return copySymlink(source, destination) })
+	default:
+		err = c.replace(exists, destination, func() error {
+			if err := mknod(destination, &stat); err != nil {
+				return &os.PathError{Op: "mknod", Path: destination, Err: err}
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		return err
+	}
+	if c.Copied != nil {
+		c.Copied(source, destination)
+	}
+	if c.HardLink || c.SymbolicLink {
+		return nil
+	}
+	return c.preserve(source, destination, &stat, created)
+}
+
+// replace makes the destination with the function, first removing the
+// file being replaced, if there is one.
+func (c *Copier) replace(exists bool, destination string, make func() error) error {
+	if exists {
+		if err := syscall.Unlink(destination); err != nil && err != syscall.ENOENT {
+			return &os.PathError{Op: "unlink", Path: destination, Err: err}
+		}
+	}
+	return make()
+}
+
+/* copyDirectory creates the directory, unless it is copied into one that
+ * exists, and if the copier is recursive copies everything in it. Errors
+ * with the entries are handed to the Errors function, if there is one, and
+ * the other entries are still copied. */
+func (c *Copier) copyDirectory(source, destination string, stat *syscall.Stat_t, exists bool) error {
+	if !exists {
+		// The directory is kept writable until its contents are in place.
+		if err := os.Mkdir(destination, os.FileMode(stat.Mode&0777|0700)); err != nil {
+			return err
+		}
+		if c.Copied != nil {
+			c.Copied(source, destination)
+		}
+	}
+
+	if c.Recursive {
+		directory, err := os.Open(source)
+		if err != nil {
+			return err
+		}
+		names, err := directory.Readdirnames(-1)
+		directory.Close()
+		if err != nil {
+			return &os.PathError{Op: "readdir", Path: source, Err: err}
+		}
+		for _, name := range names {
+			err := c.copy(filepath.Join(source, name), filepath.Join(destination, name), false)
+			if err != nil && c.Errors != nil {
+				c.Errors(err)
+			} else if err != nil {
+				return err
+			}
+		}
+	}
+	return c.preserve(source, destination, stat, !exists)
+}
+
+// copySymlink creates a symbolic link to the target of the source.
+func copySymlink(source, destination string) error {
+	target, err := os.Readlink(source)
+	if err != nil {
+		return err
+	}
+	return os.Symlink(target, destination)
+}
+
+/* preserve copies the metadata that the options ask for from the source to
+ * the destination. Ownership is changed before the mode, which a change of
+ * owner can clear the setuid and setgid bits of, and the times are set last,
+ * since every other change updates them. A file that was not created keeps
+ * its own mode unless the mode is preserved. */
+func (c *Copier) preserve(source, destination string, stat *syscall.Stat_t, created bool) error {
+	symlink := stat.Mode&syscall.S_IFMT == syscall.S_IFLNK
+
+	if c.Ownership {
+		err := os.Lchown(destination, int(stat.Uid), int(stat.Gid))
+		if err != nil && !os.IsPermission(err) {
+			return err
+		}
+	}
+	if c.Xattr && !symlink {
+		if err := copyXattrs(source, destination); err != nil {
+			return err
+		}
+	}
+	if !symlink && (c.Mode || created) {
+		mode := uint32(stat.Mode) & 07777
+		if !c.Mode {
+			mode = uint32(stat.Mode) & 0777 &^ uint32(umask)
+		}
+		if err := syscall.Chmod(destination, mode); err != nil {
+			return &os.PathError{Op: "chmod", Path: destination, Err: err}
+		}
+	}
+	if c.Timestamps {
+		if err := setTimes(destination, stat); err != nil {
+			return &os.PathError{Op: "utimensat", Path: destination, Err: err}
+		}
+	}
+	return nil
+}
+
+// The file mode creation mask of the process.
+var umask = getUmask()
+
+// Returns the umask, which can only be read by setting it.
+func getUmask() int {
+	mask := syscall.Umask(0)
+	syscall.Umask(mask)
+	return mask
+}
+
+/* Move moves the source to the destination on another file system. The
+ * source is copied with all of its metadata to a temporary name beside the
+ * destination and renamed into place, replacing any file there, and only
+ * then removed. If the copy fails, the temporary copy is removed and the
+ * source is left untouched. */
+func Move(source, destination string) error {
+	return MoveWith(source, destination, os.Rename)
+}
+
+// MoveWith is Move with the rename that puts the copy in place, which may
+// refuse to replace the destination.
+func MoveWith(source, destination string, rename func(oldpath, newpath string) error) error {
+	var stat syscall.Stat_t
+	if err := syscall.Lstat(source, &stat); err != nil {
+		return &os.PathError{Op: "lstat", Path: source, Err: err}
+	}
+	directory, base := filepath.Split(destination)
+	random := rand.New(rand.NewSource(time.Now().UnixNano() + int64(os.Getpid())))
+
+	for attempt := 0; ; attempt++ {
+		temporary := filepath.Join(directory, fmt.Sprintf(".%s.%06d~", base, random.Intn(1000000)))
+
+		// A directory is made first, so that the copy goes into a directory
+		// of our own rather than one that already had the name; any other
+		// file is created by the copy, which fails if the name is taken.
+		var err error
+		if stat.Mode&syscall.S_IFMT == syscall.S_IFDIR {
+			err = os.Mkdir(temporary, 0700)
+		}
+		if err == nil {
+			err = New(All).Copy(source, temporary)
+		}
+		if err != nil && os.IsExist(err) && !isOurs(err, temporary) {
+			if attempt < 100 {
+				continue // The temporary name is taken; try another.
+			}
+			return err
+		}
+		if err != nil {
+			os.RemoveAll(temporary)
+			return err
+		}
+
+		if err := rename(temporary, destination); err != nil {
+			os.RemoveAll(temporary)
+			return err
+		}
+		return os.RemoveAll(source)
+	}
+}
+
+// Returns true if the error is about a file inside the temporary copy rather
+// than the temporary name itself, in which case the copy is ours to remove.
+func isOurs(err error, temporary string) bool {
+	switch e := err.(type) {
+	case *os.PathError:
+		return e.Path != temporary
+	case *os.LinkError:
+		return e.New != temporary
+	}
+	return true
+}
@@ -0,0 +1,132 @@
+//
+// regular.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Michael Murphy
+//
+
+package filecopy
+
+import "fmt"
+import "io"
+import "os"
+import "syscall"
+
+const BUFFER_SIZE = 128 * 1024 // Bytes copied at a time
+
+// Returns true if every byte of the block is zero.
+func isZero(block []byte) bool {
+	for _, b := range block {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+/* copyBlocks copies in from the offset to its end, however far that turns
+ * out to be, and returns where the end was. If sparse, blocks of zeros are
+ * not written, leaving holes in out. Being read to the end, files that
+ * report no size, as those in /proc do, are copied whole. */
+func copyBlocks(out, in *os.File, offset int64, sparse bool, blockSize int) (int64, error) {
+	buffer := make([]byte, BUFFER_SIZE)
+	for {
+		n, err := in.ReadAt(buffer, offset)
+		for start := 0; start < n; start += blockSize {
+			block := buffer[start:n]
+			if len(block) > blockSize {
+				block = block[:blockSize]
+			}
+			if sparse && isZero(block) {
+				continue
+			}
+			if _, err := out.WriteAt(block, offset+int64(start)); err != nil {
+				return offset, err
+			}
+		}
+		offset += int64(n)
+		if err == io.EOF {
+			return offset, nil
+		}
+		if err != nil {
+			return offset, err
+		}
+	}
+}
+
+/* openDestination opens the destination for writing. One that exists is
+ * truncated and keeps its mode, unless it cannot be opened and the copier
+ * is forced, in which case it is removed and created again. The returned
+ * bool is true if the file was created. */
+func (c *Copier) openDestination(destination string, exists bool) (*os.File, bool, error) {
+	if exists {
+		out, err := os.OpenFile(destination, os.O_WRONLY|os.O_TRUNC, 0)
+		if err == nil {
+			return out, false, nil
+		}
+		if _, statErr := os.Stat(destination); os.IsNotExist(statErr) {
+			return nil, false, fmt.Errorf("not writing through dangling symlink '%s'", destination)
+		}
+		if !c.Force {
+			return nil, false, err
+		}
+		if err := syscall.Unlink(destination); err != nil && err != syscall.ENOENT {
+			return nil, false, &os.PathError{Op: "unlink", Path: destination, Err: err}
+		}
+	}
+	out, err := os.OpenFile(destination, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, false, &os.PathError{Op: "create", Path: destination, Err: underlying(err)}
+	}
+	return out, true, nil
+}
+
+// Returns the error of the system call behind a path error.
+func underlying(err error) error {
+	if pathError, ok := err.(*os.PathError); ok {
+		return pathError.Err
+	}
+	return err
+}
+
+/* copyRegular copies the contents of a regular file to the destination,
+ * which is created unless it exists, and returns whether it was created.
+ * The data is cloned where the file system can and the copier allows it,
+ * and is otherwise copied as copyData does. A destination created here is
+ * removed again if the copy fails, rather than left empty or cut short. */
+func (c *Copier) copyRegular(source, destination string, stat *syscall.Stat_t, exists bool) (bool, error) {
+	in, err := os.Open(source)
+	if err != nil {
+		return false, &os.PathError{Op: "open", Path: source, Err: underlying(err)}
+	}
+	defer in.Close()
+
+	out, created, err := c.openDestination(destination, exists)
+	if err != nil {
+		return false, err
+	}
+	err = c.copyContents(out, in, source, destination, stat)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil && created {
+		syscall.Unlink(destination)
+		return false, err
+	}
+	return created, err
+}
+
+// copyContents clones or copies the data of in to out, as copyRegular
+// does.
+func (c *Copier) copyContents(out, in *os.File, source, destination string, stat *syscall.Stat_t) error {
+	if c.Reflink != REFLINK_NEVER {
+		err := clone(out, in)
+		if err == nil {
+			return nil
+		}
+		if c.Reflink == REFLINK_ALWAYS {
+			return &os.LinkError{Op: "clone", Old: source, New: destination, Err: err}
+		}
+	}
+	return c.copyData(out, in, stat)
+}
@@ -0,0 +1,17 @@
+//
+// stat_atim.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Michael Murphy
+//
+
+// +build linux openbsd dragonfly solaris
+
+package filecopy
+
+import "syscall"
+
+// Returns the access and modification times of the status.
+func statTimes(stat *syscall.Stat_t) [2]syscall.Timespec {
+	return [2]syscall.Timespec{stat.Atim, stat.Mtim}
+}
@@ -0,0 +1,60 @@
+//
+// syscalls_linux.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Michael Murphy
+//
+
+// +build linux
+
+package filecopy
+
+import "os"
+import "syscall"
+import "unsafe"
+
+// The directory and flag arguments of utimensat(2).
+const (
+	AT_FDCWD            = -100
+	AT_SYMLINK_NOFOLLOW = 0x100
+)
+
+// utimensat sets the access and modification times of the file.
+func utimensat(name string, times *[2]syscall.Timespec, flags int) error {
+	path, err := syscall.BytePtrFromString(name)
+	if err != nil {
+		return err
+	}
+	fd := AT_FDCWD
+	_, _, errno := syscall.Syscall6(syscall.SYS_UTIMENSAT, uintptr(fd), uintptr(unsafe.Pointer(path)),
+		uintptr(unsafe.Pointer(times)), uintptr(flags), 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// setTimes gives the file, or the symbolic link itself, the access and
+// modification times of the status.
+func setTimes(name string, stat *syscall.Stat_t) error {
+	times := [2]syscall.Timespec{stat.Atim, stat.Mtim}
+	return utimensat(name, &times, AT_SYMLINK_NOFOLLOW)
+}
+
+// mknod makes a special file, a device, FIFO or socket, like the status.
+func mknod(name string, stat *syscall.Stat_t) error {
+	return syscall.Mknod(name, stat.Mode, int(stat.Rdev))
+}
+
+// The ioctl(2) request that makes a file share the data of another, where
+// the file system supports it, as Btrfs and XFS do.
+const FICLONE = 0x40049409
+
+// clone makes the data of out that of in, sharing its blocks.
+func clone(out, in *os.File) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, out.Fd(), FICLONE, in.Fd())
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
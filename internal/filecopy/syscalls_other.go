@@ -0,0 +1,41 @@
+//
+// syscalls_other.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Michael Murphy
+//
+
+// +build !linux
+
+package filecopy
+
+import "os"
+import "syscall"
+import "time"
+
+/* setTimes gives the file the access and modification times of the status.
+ * Without utimensat(2) the times of a symbolic link itself cannot be set,
+ * and the link keeps the times it was made with. */
+func setTimes(name string, stat *syscall.Stat_t) error {
+	if stat.Mode&syscall.S_IFMT == syscall.S_IFLNK {
+		return nil
+	}
+	times := statTimes(stat)
+	err := os.Chtimes(name, time.Unix(int64(times[0].Sec), int64(times[0].Nsec)),
+		time.Unix(int64(times[1].Sec), int64(times[1].Nsec)))
+	return underlying(err)
+}
+
+// mknod makes a FIFO like the status. The devices and sockets that
+// mknod(2) makes elsewhere cannot be made in the same way on every system.
+func mknod(name string, stat *syscall.Stat_t) error {
+	if stat.Mode&syscall.S_IFMT != syscall.S_IFIFO {
+		return syscall.ENOTSUP
+	}
+	return syscall.Mkfifo(name, uint32(stat.Mode)&07777)
+}
+
+// clone cannot share data here, so the data is always copied.
+func clone(out, in *os.File) error {
+	return syscall.ENOTSUP
+}
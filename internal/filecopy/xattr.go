@@ -0,0 +1,72 @@
+//
+// xattr.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Michael Murphy
+//
+
+// +build linux
+
+package filecopy
+
+import "bytes"
+import "os"
+import "strings"
+import "syscall"
+
+// Returns the result of a list or get call into a buffer, growing the
+// buffer until the result fits.
+func readXattr(call func([]byte) (int, error)) ([]byte, error) {
+	size, err := call(nil)
+	for err == nil {
+		buffer := make([]byte, size)
+		var n int
+		n, err = call(buffer)
+		if err == nil {
+			return buffer[:n], nil
+		}
+		if err == syscall.ERANGE {
+			size, err = call(nil)
+		}
+	}
+	return nil, err
+}
+
+/* copyXattrs copies the extended attributes of the source to the
+ * destination. A file system without them has none to copy, and attributes
+ * in the trusted and security namespaces are skipped when the process may
+ * not set them. */
+func copyXattrs(source, destination string) error {
+	list, err := readXattr(func(buffer []byte) (int, error) {
+		return syscall.Listxattr(source, buffer)
+	})
+	if err == syscall.ENOTSUP {
+		return nil
+	}
+	if err != nil {
+		return &os.PathError{Op: "listxattr", Path: source, Err: err}
+	}
+
+	for _, name := range bytes.Split(list, []byte{0}) {
+		if len(name) == 0 {
+			continue
+		}
+		attribute := string(name)
+		value, err := readXattr(func(buffer []byte) (int, error) {
+			return syscall.Getxattr(source, attribute, buffer)
+		})
+		if err != nil {
+			return &os.PathError{Op: "getxattr", Path: source, Err: err}
+		}
+
+		err = syscall.Setxattr(destination, attribute, value, 0)
+		privileged := strings.HasPrefix(attribute, "trusted.") || strings.HasPrefix(attribute, "security.")
+		switch {
+		case err == nil, err == syscall.ENOTSUP:
+		case privileged && (err == syscall.EPERM || err == syscall.EACCES):
+		default:
+			return &os.PathError{Op: "setxattr", Path: destination, Err: err}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+//
+// xattr_other.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Michael Murphy
+//
+
+// +build !linux
+
+package filecopy
+
+// copyXattrs has no extended attributes to copy where the system calls
+// for them are not at hand.
+func copyXattrs(source, destination string) error {
+	return nil
+}
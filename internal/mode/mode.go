@@ -0,0 +1,215 @@
+//
+// mode.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Corey Prak
+//
+
+// Package mode parses and applies file mode changes, written in octal as in
+// 755 or symbolically as in u=rwx,g+s, the way chmod, install, mkdir and
+// mkfifo take them. It follows the rules of GNU's modechange.
+package mode
+
+import "fmt"
+import "syscall"
+
+// The bits of a file mode that a change can touch.
+const (
+	S_ISUID  = 04000
+	S_ISGID  = 02000
+	S_ISVTX  = 01000
+	S_IRWXU  = 00700
+	S_IRWXG  = 00070
+	S_IRWXO  = 00007
+	S_IRUGO  = 00444
+	S_IWUGO  = 00222
+	S_IXUGO  = 00111
+	ALL_BITS = 07777
+)
+
+// How a change finds the bits it sets.
+const (
+	ORDINARY = iota // Use the bits given
+	COPY            // Copy the bits of a class, as in g=u
+	X_IF_ANY        // Execute only for directories or files some may execute, as in +X
+)
+
+/* A change is one operation of a mode: '=', '+' or '-' of the value, on the
+ * bits of affected, or on those allowed by the umask if no class was named.
+ * The mentioned bits are those the change names, which decides whether the
+ * setuid and setgid bits of a directory are left alone. */
+type change struct {
+	op        byte
+	flag      int
+	affected  uint32
+	value     uint32
+	mentioned uint32
+}
+
+// A Mode is a list of changes to make to a file mode, in order.
+type Mode []change
+
+// Parse parses an octal or symbolic mode.
+func Parse(s string) (Mode, error) {
+	invalid := fmt.Errorf("invalid mode '%s'", s)
+	if s == "" {
+		return nil, invalid
+	}
+
+	if s[0] >= '0' && s[0] <= '7' {
+		var octal uint32
+		for _, c := range s {
+			if c < '0' || c > '7' {
+				return nil, invalid
+			}
+			octal = octal*8 + uint32(c-'0')
+			if octal > ALL_BITS {
+				return nil, invalid
+			}
+		}
+		// Fewer than five digits leave the setuid and setgid bits of a
+		// directory alone unless they are set.
+		mentioned := uint32(ALL_BITS)
+		if len(s) < 5 {
+			mentioned = octal&(S_ISUID|S_ISGID) | S_ISVTX | S_IRWXU | S_IRWXG | S_IRWXO
+		}
+		return Mode{{op: '=', affected: ALL_BITS, value: octal, mentioned: mentioned}}, nil
+	}
+
+	var mode Mode
+	index := 0
+	for {
+		var affected uint32
+	who:
+		for ; index < len(s); index++ {
+			switch s[index] {
+			case 'u':
+				affected |= S_ISUID | S_IRWXU
+			case 'g':
+				affected |= S_ISGID | S_IRWXG
+			case 'o':
+				affected |= S_ISVTX | S_IRWXO
+			case 'a':
+				affected |= ALL_BITS
+			default:
+				break who
+			}
+		}
+
+		if index >= len(s) || (s[index] != '=' && s[index] != '+' && s[index] != '-') {
+			return nil, invalid
+		}
+		for index < len(s) && (s[index] == '=' || s[index] == '+' || s[index] == '-') {
+			c := change{op: s[index], affected: affected}
+			index++
+			if index < len(s) && (s[index] == 'u' || s[index] == 'g' || s[index] == 'o') {
+				c.flag = COPY
+				c.value = map[byte]uint32{'u': S_IRWXU, 'g': S_IRWXG, 'o': S_IRWXO}[s[index]]
+				index++
+			} else {
+			permissions:
+				for ; index < len(s); index++ {
+					switch s[index] {
+					case 'r':
+						c.value |= S_IRUGO
+					case 'w':
+						c.value |= S_IWUGO
+					case 'x':
+						c.value |= S_IXUGO
+					case 'X':
+						c.flag = X_IF_ANY
+					case 's':
+						c.value |= S_ISUID | S_ISGID
+					case 't':
+						c.value |= S_ISVTX
+					default:
+						break permissions
+					}
+				}
+			}
+			c.mentioned = c.value
+			if affected != 0 {
+				c.mentioned = affected & c.value
+			}
+			mode = append(mode, c)
+		}
+
+		if index == len(s) {
+			return mode, nil
+		}
+		if s[index] != ',' {
+			return nil, invalid
+		}
+		index++
+	}
+}
+
+// Returns the bits of the classes that have any of the bits in value,
+// spread to every class, as g=u copies the user bits to the group.
+func spread(value uint32) uint32 {
+	var result uint32
+	if value&S_IRUGO != 0 {
+		result |= S_IRUGO
+	}
+	if value&S_IWUGO != 0 {
+		result |= S_IWUGO
+	}
+	if value&S_IXUGO != 0 {
+		result |= S_IXUGO
+	}
+	return result
+}
+
+/* Apply returns the old mode with the changes made, and the bits that the
+ * changes set or clear. Changes that name no class are limited by the
+ * umask, and the setuid and setgid bits of a directory are only changed
+ * when the mode names them. */
+func (m Mode) Apply(old uint32, directory bool, umask uint32) (mode, bits uint32) {
+	mode = old & ALL_BITS
+	for _, c := range m {
+		var omit uint32
+		if directory {
+			omit = (S_ISUID | S_ISGID) &^ c.mentioned
+		}
+
+		value := c.value
+		switch c.flag {
+		case COPY:
+			value = spread(value & mode)
+		case X_IF_ANY:
+			if mode&S_IXUGO != 0 || directory {
+				value |= S_IXUGO
+			}
+		}
+		if c.affected != 0 {
+			value &= c.affected &^ omit
+		} else {
+			value &= ^umask &^ omit
+		}
+
+		switch c.op {
+		case '=':
+			preserved := omit
+			if c.affected != 0 {
+				preserved |= ^c.affected
+			}
+			bits |= ALL_BITS &^ preserved
+			mode = mode&preserved | value
+		case '+':
+			bits |= value
+			mode |= value
+		case '-':
+			bits |= value
+			mode &^= value
+		}
+	}
+	return mode, bits
+}
+
+// Umask returns the file mode creation mask of the process, which can only
+// be read by setting it.
+func Umask() uint32 {
+	mask := syscall.Umask(0)
+	syscall.Umask(mask)
+	return uint32(mask)
+}
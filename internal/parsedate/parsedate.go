@@ -0,0 +1,208 @@
+//
+// parsedate.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Michael Murphy
+//
+
+// Package parsedate parses the free-form date strings accepted by GNU date -d
+// and touch -d, such as "2014-06-19 03:55", "next friday", "@1403164549",
+// "3 days ago" or 'TZ="Europe/Paris" tomorrow 9am'.
+package parsedate
+
+import "fmt"
+import "strings"
+import "time"
+
+// A date holds the items of a date string as they are parsed.
+type date struct {
+	year, month, day          int
+	yearDigits                int
+	hour, minute, second      int
+	nanosecond                int
+	meridian                  int // 0 for a 24-hour clock, 1 for am, 2 for pm
+	weekday, weekdayOrdinal   int
+	zoneOffset                int
+	zoneName                  string
+	relYear, relMonth, relDay int
+	relDuration               time.Duration
+	epoch                     *time.Time
+
+	datesSeen, timesSeen, daysSeen, zonesSeen, relsSeen int
+}
+
+// Parse parses the date string relative to now. Wall-clock times are taken to
+// be in the location of now, unless the string names a zone or begins with
+// TZ="Zone". The result is in the location of now.
+func Parse(input string, now time.Time) (time.Time, error) {
+	return ParseDebug(input, now, nil)
+}
+
+// ParseDebug is like Parse, but also calls debug with a line that explains
+// each step of the parse, as date --debug prints them.
+func ParseDebug(input string, now time.Time, debug func(string)) (time.Time, error) {
+	trace := tracer(debug)
+	location := now.Location()
+	zoneSource := DescribeZone(location)
+	rest := strings.TrimSpace(input)
+	if strings.HasPrefix(rest, `TZ="`) {
+		end := strings.Index(rest[4:], `"`)
+		if end < 0 {
+			trace.printf("error: missing closing '\"' in TZ=\"...\"")
+			return time.Time{}, invalid(input)
+		}
+		zone, err := LoadZone(rest[4 : 4+end])
+		if err != nil {
+			trace.printf("error: %s", err)
+			return time.Time{}, invalid(input)
+		}
+		location = zone
+		zoneSource = fmt.Sprintf(`TZ="%s" in date string`, rest[4:4+end])
+		rest = rest[4+end+1:]
+	}
+
+	p := &parser{tokens: tokenize(rest), now: now.In(location), trace: trace}
+	d, err := p.parse()
+	if err != nil {
+		trace.printf("error: %s", err)
+		trace.printf("error: parsing failed")
+		return time.Time{}, invalid(input)
+	}
+	switch {
+	case d.epoch != nil:
+		zoneSource = "'@timespec' - always UTC"
+	case d.zonesSeen > 0:
+		zoneSource = fmt.Sprintf("parsed date/time string (%s)", formatOffset(d.zoneOffset))
+	}
+	trace.printf("input timezone: %s", zoneSource)
+
+	t, err := d.resolve(now.In(location), trace)
+	if err != nil {
+		trace.printf("error: %s", err)
+		return time.Time{}, invalid(input)
+	}
+	return t.In(now.Location()), nil
+}
+
+// A tracer receives the lines of debugging output, if there is a tracer.
+type tracer func(string)
+
+// printf formats a line of debugging output.
+func (trace tracer) printf(format string, a ...interface{}) {
+	if trace != nil {
+		trace(fmt.Sprintf(format, a...))
+	}
+}
+
+// Returns the time as GNU date --debug shows it, with the zone offset if
+// withZone is set.
+func debugTime(t time.Time, withZone bool) string {
+	s := t.Format("(Y-M-D) 2006-01-02 15:04:05")
+	if withZone {
+		_, offset := t.Zone()
+		s += " TZ=" + formatOffset(offset)
+	}
+	return s
+}
+
+// Returns the error for a date string that cannot be parsed.
+func invalid(input string) error {
+	return fmt.Errorf("invalid date '%s'", input)
+}
+
+// Returns the number of days in the month of the year.
+func daysIn(year, month int) int {
+	return time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// resolve computes the time that the parsed items describe, relative to now.
+func (d *date) resolve(now time.Time, trace tracer) (time.Time, error) {
+	if d.epoch != nil {
+		return *d.epoch, nil
+	}
+	if d.datesSeen > 1 || d.timesSeen > 1 || d.daysSeen > 1 || d.zonesSeen > 1 {
+		return time.Time{}, fmt.Errorf("conflicting items")
+	}
+
+	year, month, day := now.Date()
+	if d.datesSeen > 0 {
+		if d.yearDigits != 0 {
+			year = d.year
+		}
+		month, day = time.Month(d.month), d.day
+		if d.month < 1 || d.month > 12 || d.day < 1 || d.day > daysIn(year, d.month) {
+			return time.Time{}, fmt.Errorf("invalid date (Y-M-D) %04d-%02d-%02d", year, d.month, d.day)
+		}
+	}
+
+	hour, minute, second, nanosecond := now.Hour(), now.Minute(), now.Second(), now.Nanosecond()
+	switch {
+	case d.timesSeen > 0:
+		switch {
+		case d.meridian != 0 && (d.hour < 1 || d.hour > 12):
+			return time.Time{}, fmt.Errorf("invalid hour %d", d.hour)
+		case d.meridian == 1:
+			d.hour %= 12
+		case d.meridian == 2:
+			d.hour = d.hour%12 + 12
+		}
+		if d.hour > 23 || d.minute > 59 || d.second > 60 {
+			return time.Time{}, fmt.Errorf("invalid time %02d:%02d:%02d", d.hour, d.minute, d.second)
+		}
+		hour, minute, second, nanosecond = d.hour, d.minute, d.second, d.nanosecond
+		trace.printf("using specified time as starting value: '%02d:%02d:%02d'", hour, minute, second)
+	// Any item but a relative one starts from midnight rather than now.
+	case d.datesSeen > 0 || d.daysSeen > 0 || (d.zonesSeen > 0 && d.relsSeen == 0):
+		hour, minute, second, nanosecond = 0, 0, 0, 0
+		trace.printf("warning: using midnight as starting time: 00:00:00")
+	default:
+		trace.printf("using current time as starting value: '%02d:%02d:%02d'", hour, minute, second)
+	}
+	if d.datesSeen == 0 && d.daysSeen == 0 {
+		trace.printf("using current date as starting value: '(Y-M-D) %04d-%02d-%02d'", year, month, day)
+	}
+
+	location := now.Location()
+	if d.zonesSeen > 0 {
+		location = time.FixedZone(d.zoneName, d.zoneOffset)
+	}
+	t := time.Date(year, month, day, hour, minute, second, nanosecond, location)
+	if t.Hour() != hour || t.Minute() != minute {
+		return time.Time{}, fmt.Errorf("invalid date/time: '(Y-M-D) %04d-%02d-%02d %02d:%02d:%02d' "+
+			"does not exist in this time zone", year, month, day, hour, minute, second)
+	}
+
+	// A day of the week moves forward to the next such day, or backward
+	// for a negative ordinal such as "last friday".
+	if d.daysSeen > 0 && d.datesSeen == 0 {
+		current := int(t.Weekday())
+		days := (d.weekday-current+7)%7 + 7*d.weekdayOrdinal
+		if d.weekdayOrdinal > 0 && current != d.weekday {
+			days -= 7
+		}
+		t = t.AddDate(0, 0, days)
+		trace.printf("new start date: '%s' is '%s'", d.describeWeekday(), debugTime(t, false))
+	}
+	trace.printf("starting date/time: '%s'", debugTime(t, d.zonesSeen > 0))
+
+	if d.relYear != 0 || d.relMonth != 0 || d.relDay != 0 {
+		if (d.relYear != 0 || d.relMonth != 0) && t.Day() != 15 {
+			trace.printf("warning: when adding relative months/years, " +
+				"it is recommended to specify the 15th of the months")
+		}
+		t = t.AddDate(d.relYear, d.relMonth, d.relDay)
+		trace.printf("after date adjustment (%+d years, %+d months, %+d days),", d.relYear, d.relMonth, d.relDay)
+		trace.printf("    new date/time = '%s'", debugTime(t, d.zonesSeen > 0))
+	}
+	trace.printf("'%s' = %d epoch-seconds", debugTime(t, d.zonesSeen > 0), t.Unix())
+
+	if d.relDuration != 0 {
+		t = t.Add(d.relDuration)
+		hours, minutes := d.relDuration/time.Hour, d.relDuration%time.Hour/time.Minute
+		seconds, nanoseconds := d.relDuration%time.Minute/time.Second, d.relDuration%time.Second
+		trace.printf("after time adjustment (%+d hours, %+d minutes, %+d seconds, %+d ns),",
+			hours, minutes, seconds, nanoseconds)
+		trace.printf("    new time = %d epoch-seconds", t.Unix())
+	}
+	return t, nil
+}
@@ -0,0 +1,682 @@
+//
+// parser.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Michael Murphy
+//
+package parsedate
+
+import "fmt"
+import "strings"
+import "time"
+
+// The kinds of token a date string is split into.
+const (
+	END = iota
+	NUMBER
+	WORD
+	PUNCTUATION
+)
+
+// A token is a number, which may carry a sign, a word, which is lower case
+// with any dots removed, or a single punctuation character.
+type token struct {
+	kind   int
+	text   string
+	value  int
+	digits int
+	sign   int // -1 or 1 for a signed number, 0 if there was no sign
+}
+
+// The units of relative items, for a count of one.
+type unit struct {
+	years, months, days int
+	duration            time.Duration
+}
+
+var units = map[string]unit{
+	"year":      {years: 1},
+	"month":     {months: 1},
+	"fortnight": {days: 14},
+	"week":      {days: 7},
+	"day":       {days: 1},
+	"hour":      {duration: time.Hour},
+	"minute":    {duration: time.Minute},
+	"min":       {duration: time.Minute},
+	"second":    {duration: time.Second},
+	"sec":       {duration: time.Second},
+}
+
+// Words that stand for a relative number of days.
+var relativeDays = map[string]int{
+	"yesterday": -1,
+	"today":     0,
+	"now":       0,
+	"tomorrow":  1,
+}
+
+// Words that give the count of a relative item or day of the week. "second"
+// is missing because it is taken as a unit.
+var ordinals = map[string]int{
+	"last": -1, "this": 0, "next": 1, "first": 1, "third": 3, "fourth": 4,
+	"fifth": 5, "sixth": 6, "seventh": 7, "eighth": 8, "ninth": 9,
+	"tenth": 10, "eleventh": 11, "twelfth": 12,
+}
+
+var months = map[string]int{
+	"january": 1, "february": 2, "march": 3, "april": 4, "may": 5,
+	"june": 6, "july": 7, "august": 8, "september": 9, "sept": 9,
+	"october": 10, "november": 11, "december": 12,
+}
+
+var weekdays = map[string]int{
+	"sunday": 0, "monday": 1, "tuesday": 2, "tues": 2, "wednesday": 3,
+	"wednes": 3, "thursday": 4, "thur": 4, "thurs": 4, "friday": 5,
+	"saturday": 6,
+}
+
+// The time zone abbreviations, as offsets east of UTC in seconds, and whether
+// they are daylight saving zones, which cannot be followed by "dst".
+var zones = map[string]struct {
+	offset   int
+	daylight bool
+}{
+	"utc": {0, false}, "ut": {0, false}, "gmt": {0, false}, "z": {0, false},
+	"wet": {0, false}, "west": {3600, true}, "bst": {3600, true},
+	"cet": {3600, false}, "cest": {7200, true}, "met": {3600, false},
+	"mest": {7200, true}, "eet": {7200, false}, "eest": {10800, true},
+	"msk": {10800, false}, "ist": {19800, false}, "jst": {32400, false},
+	"aest": {36000, false}, "aedt": {39600, true}, "nzst": {43200, false},
+	"nzdt": {46800, true}, "ast": {-14400, false}, "adt": {-10800, true},
+	"est": {-18000, false}, "edt": {-14400, true}, "cst": {-21600, false},
+	"cdt": {-18000, true}, "mst": {-25200, false}, "mdt": {-21600, true},
+	"pst": {-28800, false}, "pdt": {-25200, true}, "akst": {-32400, false},
+	"akdt": {-28800, true}, "hst": {-36000, false},
+}
+
+// Returns true if the byte is an ASCII letter.
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// Returns true if the byte is an ASCII digit.
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+/* tokenize splits the date string into tokens. Whitespace separates tokens
+ * and is otherwise ignored, as is any text in parentheses. A '+' or '-'
+ * followed by digits, possibly after whitespace, makes a signed number. */
+func tokenize(input string) []token {
+	var tokens []token
+	for index := 0; index < len(input); {
+		c := input[index]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f':
+			index++
+		case c == '(':
+			for depth := 0; index < len(input); index++ {
+				if input[index] == '(' {
+					depth++
+				} else if input[index] == ')' {
+					if depth--; depth == 0 {
+						index++
+						break
+					}
+				}
+			}
+		case isDigit(c) || c == '+' || c == '-':
+			sign, start := 0, index
+			if !isDigit(c) {
+				start++
+				for start < len(input) && (input[start] == ' ' || input[start] == '\t') {
+					start++
+				}
+				if start == len(input) || !isDigit(input[start]) {
+					tokens = append(tokens, token{kind: PUNCTUATION, text: string(c)})
+					index++
+					continue
+				}
+				sign = 1
+				if c == '-' {
+					sign = -1
+				}
+			}
+			end := start
+			value := 0
+			for ; end < len(input) && isDigit(input[end]); end++ {
+				value = value*10 + int(input[end]-'0')
+			}
+			tokens = append(tokens, token{NUMBER, input[start:end], value, end - start, sign})
+			index = end
+		case isLetter(c):
+			end := index
+			for end < len(input) && (isLetter(input[end]) || input[end] == '.') {
+				end++
+			}
+			word := strings.ToLower(strings.Replace(input[index:end], ".", "", -1))
+			tokens = append(tokens, token{kind: WORD, text: word})
+			index = end
+		default:
+			tokens = append(tokens, token{kind: PUNCTUATION, text: string(c)})
+			index++
+		}
+	}
+	return tokens
+}
+
+// A parser turns the tokens of a date string into its items.
+type parser struct {
+	tokens   []token
+	position int
+	d        date
+	now      time.Time
+	trace    tracer
+}
+
+// Returns the token offset places ahead, or an END token past the last one.
+func (p *parser) peek(offset int) token {
+	if p.position+offset >= len(p.tokens) {
+		return token{kind: END}
+	}
+	return p.tokens[p.position+offset]
+}
+
+// Returns the next token and moves past it.
+func (p *parser) next() token {
+	t := p.peek(0)
+	p.position++
+	return t
+}
+
+// Returns true, moving past it, if the next token is the punctuation.
+func (p *parser) accept(punctuation string) bool {
+	if t := p.peek(0); t.kind == PUNCTUATION && t.text == punctuation {
+		p.position++
+		return true
+	}
+	return false
+}
+
+// Returns the unit named by the word, which may be plural.
+func getUnit(word string) (unit, bool) {
+	u, ok := units[word]
+	if !ok && strings.HasSuffix(word, "s") {
+		u, ok = units[strings.TrimSuffix(word, "s")]
+	}
+	return u, ok
+}
+
+// Returns the month named by the word, in full or by its first three letters.
+func getMonth(word string) (int, bool) {
+	if month, ok := months[word]; ok {
+		return month, true
+	}
+	for name, month := range months {
+		if len(word) == 3 && strings.HasPrefix(name, word) {
+			return month, true
+		}
+	}
+	return 0, false
+}
+
+// Returns the day of the week named by the word, in full or by its first
+// three letters.
+func getWeekday(word string) (int, bool) {
+	if weekday, ok := weekdays[word]; ok {
+		return weekday, true
+	}
+	for name, weekday := range weekdays {
+		if len(word) == 3 && strings.HasPrefix(name, word) {
+			return weekday, true
+		}
+	}
+	return 0, false
+}
+
+// Returns the meridian of "am" or "pm", or 0 for any other token.
+func getMeridian(t token) int {
+	switch {
+	case t.kind == WORD && t.text == "am":
+		return 1
+	case t.kind == WORD && t.text == "pm":
+		return 2
+	}
+	return 0
+}
+
+// parse parses every item of the date string.
+func (p *parser) parse() (*date, error) {
+	if p.accept("@") {
+		return &p.d, p.epoch()
+	}
+	for p.peek(0).kind != END {
+		before := p.d
+		if err := p.item(); err != nil {
+			return nil, err
+		}
+		p.describe(&before)
+	}
+	return &p.d, nil
+}
+
+// The names of the ordinals as date --debug shows them.
+var ordinalNames = map[int]string{-1: "last", 1: "next/first"}
+
+// Describes the day of the week of a date, as in "next/first Fri".
+func (d *date) describeWeekday() string {
+	name := time.Weekday(d.weekday).String()[:3]
+	if ordinal, ok := ordinalNames[d.weekdayOrdinal]; ok {
+		return ordinal + " " + name
+	} else if d.weekdayOrdinal != 0 {
+		return fmt.Sprintf("%d %s", d.weekdayOrdinal, name)
+	}
+	return name
+}
+
+// describe traces the parts of the date that the last item added, given the
+// date as it was before the item.
+func (p *parser) describe(before *date) {
+	if p.trace == nil {
+		return
+	}
+	d := &p.d
+	if d.datesSeen != before.datesSeen {
+		year := p.now.Year()
+		if d.yearDigits != 0 {
+			year = d.year
+		}
+		p.trace.printf("parsed date part: (Y-M-D) %04d-%02d-%02d", year, d.month, d.day)
+	}
+	if d.timesSeen != before.timesSeen {
+		part := fmt.Sprintf("%02d:%02d:%02d", d.hour, d.minute, d.second)
+		if d.nanosecond != 0 {
+			part += fmt.Sprintf(".%09d", d.nanosecond)
+		}
+		if d.meridian != 0 {
+			part += []string{"", " am", " pm"}[d.meridian]
+		}
+		if d.zonesSeen != before.zonesSeen {
+			part += " TZ=" + formatOffset(d.zoneOffset)
+		}
+		p.trace.printf("parsed time part: %s", part)
+	} else if d.zonesSeen != before.zonesSeen {
+		p.trace.printf("parsed zone part: UTC%s", formatOffset(d.zoneOffset))
+	}
+	if d.daysSeen != before.daysSeen {
+		p.trace.printf("parsed day part: %s (day ordinal=%d number=%d)",
+			d.describeWeekday(), d.weekdayOrdinal, d.weekday)
+	}
+	if d.relsSeen != before.relsSeen {
+		var parts []string
+		for _, part := range []struct {
+			value int64
+			unit  string
+		}{
+			{int64(d.relYear - before.relYear), "year"},
+			{int64(d.relMonth - before.relMonth), "month"},
+			{int64(d.relDay - before.relDay), "day"},
+			{int64((d.relDuration - before.relDuration) / time.Hour), "hour"},
+			{int64((d.relDuration - before.relDuration) % time.Hour / time.Minute), "minute"},
+			{int64((d.relDuration - before.relDuration) % time.Minute / time.Second), "second"},
+		} {
+			if part.value != 0 {
+				parts = append(parts, fmt.Sprintf("%+d %s(s)", part.value, part.unit))
+			}
+		}
+		if len(parts) == 0 {
+			parts = append(parts, "today/this/now")
+		}
+		p.trace.printf("parsed relative part: %s", strings.Join(parts, " "))
+	}
+}
+
+// epoch parses the seconds since the epoch, with an optional fraction, that
+// follow an '@'. Nothing may follow them.
+func (p *parser) epoch() error {
+	seconds := p.next()
+	if seconds.kind != NUMBER {
+		return fmt.Errorf("expected seconds")
+	}
+	sign := int64(1)
+	if seconds.sign < 0 {
+		sign = -1
+	}
+	var nanoseconds int
+	if p.accept(".") || p.accept(",") {
+		fraction := p.next()
+		if fraction.kind != NUMBER || fraction.sign != 0 {
+			return fmt.Errorf("expected fraction")
+		}
+		nanoseconds = getNanoseconds(fraction.text)
+	}
+	if p.peek(0).kind != END {
+		return fmt.Errorf("unexpected '%s'", p.peek(0).text)
+	}
+	p.trace.printf("parsed number of seconds part: number of seconds: %d", sign*int64(seconds.value))
+	t := time.Unix(sign*int64(seconds.value), sign*int64(nanoseconds))
+	p.d.epoch = &t
+	return nil
+}
+
+// Returns the digits of a fraction of a second as nanoseconds.
+func getNanoseconds(digits string) int {
+	if len(digits) > 9 {
+		digits = digits[:9]
+	}
+	nanoseconds := 0
+	for index := 0; index < 9; index++ {
+		nanoseconds *= 10
+		if index < len(digits) {
+			nanoseconds += int(digits[index] - '0')
+		}
+	}
+	return nanoseconds
+}
+
+// item parses the next item of the date string.
+func (p *parser) item() error {
+	t := p.next()
+	switch t.kind {
+	case WORD:
+		return p.word(t)
+	case NUMBER:
+		return p.number(t)
+	case PUNCTUATION:
+		if t.text == "," {
+			return nil
+		}
+	}
+	return fmt.Errorf("unexpected '%s'", t.text)
+}
+
+// word parses an item that starts with a word.
+func (p *parser) word(t token) error {
+	if days, ok := relativeDays[t.text]; ok {
+		p.d.relDay += days
+		p.d.relsSeen++
+		return nil
+	}
+	if t.text == "t" && p.peek(0).kind == NUMBER && p.peek(0).sign == 0 {
+		return nil // The 'T' between the date and time of ISO 8601
+	}
+	if month, ok := getMonth(t.text); ok {
+		return p.monthDate(month)
+	}
+	if weekday, ok := getWeekday(t.text); ok {
+		p.setWeekday(weekday, 0)
+		return nil
+	}
+	if ordinal, ok := ordinals[t.text]; ok {
+		following := p.next()
+		if weekday, ok := getWeekday(following.text); ok && following.kind == WORD {
+			p.setWeekday(weekday, ordinal)
+			return nil
+		}
+		if u, ok := getUnit(following.text); ok && following.kind == WORD {
+			p.relative(ordinal, u)
+			return nil
+		}
+		return fmt.Errorf("unexpected '%s'", following.text)
+	}
+	if u, ok := getUnit(t.text); ok {
+		p.relative(1, u)
+		return nil
+	}
+	if zone, ok := zones[t.text]; ok {
+		p.d.zonesSeen++
+		p.d.zoneName = strings.ToUpper(t.text)
+		p.d.zoneOffset = zone.offset
+		if following := p.peek(0); following.kind == WORD && following.text == "dst" && !zone.daylight {
+			p.position++
+			p.d.zoneOffset += 3600
+		} else if following.kind == NUMBER && following.sign != 0 {
+			p.position++
+			p.d.zoneOffset += p.zoneOffset(following)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown word '%s'", strings.ToUpper(t.text))
+}
+
+// Records a day of the week, which moves the date forward to that day, or by
+// further weeks for an ordinal.
+func (p *parser) setWeekday(weekday, ordinal int) {
+	p.d.weekday = weekday
+	p.d.weekdayOrdinal = ordinal
+	p.d.daysSeen++
+	p.accept(",")
+}
+
+// Records count of the unit as a relative item, negating it if "ago" follows.
+func (p *parser) relative(count int, u unit) {
+	if t := p.peek(0); t.kind == WORD && t.text == "ago" {
+		p.position++
+		count = -count
+	}
+	p.d.relYear += count * u.years
+	p.d.relMonth += count * u.months
+	p.d.relDay += count * u.days
+	p.d.relDuration += time.Duration(count) * u.duration
+	p.d.relsSeen++
+}
+
+// Records the year, taking two-digit years from 1969 to 2068.
+func (p *parser) setYear(t token) {
+	p.d.year = t.value
+	p.d.yearDigits = t.digits
+	if t.digits == 2 {
+		if t.value < 69 {
+			p.d.year += 2000
+		} else {
+			p.d.year += 1900
+		}
+	}
+}
+
+// Records a calendar date.
+func (p *parser) setDate(month, day int) {
+	p.d.month = month
+	p.d.day = day
+	p.d.datesSeen++
+}
+
+// Returns true if the token is a year: an unsigned number that does not
+// start a time of day.
+func (p *parser) isYear(offset int) bool {
+	t := p.peek(offset)
+	following := p.peek(offset + 1)
+	return t.kind == NUMBER && t.sign == 0 &&
+		!(following.kind == PUNCTUATION && following.text == ":") && getMeridian(following) == 0
+}
+
+// monthDate parses a date that starts with the name of a month, as in
+// "June 19" or "June 19, 2014".
+func (p *parser) monthDate(month int) error {
+	day := p.next()
+	if day.kind != NUMBER {
+		return fmt.Errorf("expected day")
+	}
+	if day.sign < 0 {
+		// The month and year of a date such as "Jun-19-2014".
+		p.setDate(month, -day.value)
+		if year := p.peek(0); year.kind == NUMBER && year.sign < 0 {
+			p.position++
+			p.setYear(year)
+		}
+		return nil
+	}
+	p.setDate(month, day.value)
+	p.accept(",")
+	if p.isYear(0) {
+		p.setYear(p.next())
+	}
+	return nil
+}
+
+// number parses an item that starts with a number.
+func (p *parser) number(t token) error {
+	following := p.peek(0)
+	if following.kind == WORD {
+		if u, ok := getUnit(following.text); ok {
+			p.position++
+			count := t.value
+			if t.sign < 0 {
+				count = -count
+			}
+			p.relative(count, u)
+			return nil
+		}
+	}
+	if t.sign != 0 {
+		return fmt.Errorf("unexpected signed number")
+	}
+
+	switch {
+	case following.kind == PUNCTUATION && following.text == ":":
+		return p.timeOfDay(t)
+	case following.kind == PUNCTUATION && following.text == "/":
+		return p.slashDate(t)
+	case following.kind == NUMBER && following.sign < 0 &&
+		p.peek(1).kind == NUMBER && p.peek(1).sign < 0:
+		// An ISO 8601 date such as 2014-06-19.
+		p.position += 2
+		p.setYear(t)
+		p.setDate(following.value, p.peek(-1).value)
+		return nil
+	case following.kind == PUNCTUATION && following.text == "-":
+		// A date such as 19-Jun-2014.
+		month, ok := getMonth(p.peek(1).text)
+		if !ok || p.peek(1).kind != WORD {
+			return fmt.Errorf("expected month")
+		}
+		p.position += 2
+		p.setDate(month, t.value)
+		if year := p.peek(0); year.kind == NUMBER && year.sign < 0 {
+			p.position++
+			p.setYear(year)
+		}
+		return nil
+	case getMeridian(following) != 0:
+		p.position++
+		p.d.hour, p.d.minute, p.d.second = t.value, 0, 0
+		p.d.meridian = getMeridian(following)
+		p.d.timesSeen++
+		return nil
+	case following.kind == WORD:
+		if month, ok := getMonth(following.text); ok {
+			p.position++
+			p.setDate(month, t.value)
+			if p.isYear(0) {
+				p.setYear(p.next())
+			}
+			return nil
+		}
+		if weekday, ok := getWeekday(following.text); ok {
+			p.position++
+			p.setWeekday(weekday, t.value)
+			return nil
+		}
+	}
+	p.digits(t)
+	return nil
+}
+
+/* digits interprets a number that stands alone, as GNU date does: it is the
+ * year of a date given without one, a date YYYYMMDD if it has more than four
+ * digits, and otherwise a time of day, HH or HHMM. */
+func (p *parser) digits(t token) {
+	switch {
+	case p.d.datesSeen > 0 && p.d.yearDigits == 0 && p.d.relsSeen == 0 &&
+		(p.d.timesSeen > 0 || t.digits > 2):
+		p.setYear(t)
+	case t.digits > 4:
+		p.setYear(token{value: t.value / 10000, digits: t.digits - 4})
+		p.setDate(t.value/100%100, t.value%100)
+	default:
+		if t.digits <= 2 {
+			p.d.hour, p.d.minute = t.value, 0
+		} else {
+			p.d.hour, p.d.minute = t.value/100, t.value%100
+		}
+		p.d.second, p.d.nanosecond, p.d.meridian = 0, 0, 0
+		p.d.timesSeen++
+	}
+}
+
+// slashDate parses a date such as 6/19, 6/19/2014 or 2014/6/19.
+func (p *parser) slashDate(first token) error {
+	p.position++
+	second := p.next()
+	if second.kind != NUMBER || second.sign != 0 {
+		return fmt.Errorf("expected number")
+	}
+	if !p.accept("/") {
+		p.setDate(first.value, second.value)
+		return nil
+	}
+	third := p.next()
+	if third.kind != NUMBER || third.sign != 0 {
+		return fmt.Errorf("expected number")
+	}
+	if first.digits >= 4 {
+		p.setYear(first)
+		p.setDate(second.value, third.value)
+	} else {
+		p.setYear(third)
+		p.setDate(first.value, second.value)
+	}
+	return nil
+}
+
+/* timeOfDay parses a time such as 03:55, 03:55:49.123 or 3:55pm, along with
+ * a numeric zone offset that follows it, as in 03:55-05:00. */
+func (p *parser) timeOfDay(hour token) error {
+	p.position++
+	minute := p.next()
+	if minute.kind != NUMBER || minute.sign != 0 {
+		return fmt.Errorf("expected minutes")
+	}
+	p.d.hour, p.d.minute, p.d.second, p.d.nanosecond = hour.value, minute.value, 0, 0
+	if p.accept(":") {
+		second := p.next()
+		if second.kind != NUMBER || second.sign != 0 {
+			return fmt.Errorf("expected seconds")
+		}
+		p.d.second = second.value
+		separator, fraction := p.peek(0), p.peek(1)
+		if separator.kind == PUNCTUATION && (separator.text == "." || separator.text == ",") &&
+			fraction.kind == NUMBER && fraction.sign == 0 {
+			p.position += 2
+			p.d.nanosecond = getNanoseconds(fraction.text)
+		}
+	}
+	p.d.meridian = getMeridian(p.peek(0))
+	if p.d.meridian != 0 {
+		p.position++
+	}
+	p.d.timesSeen++
+
+	if offset := p.peek(0); offset.kind == NUMBER && offset.sign != 0 {
+		p.position++
+		p.d.zonesSeen++
+		p.d.zoneName = ""
+		p.d.zoneOffset = p.zoneOffset(offset)
+	}
+	return nil
+}
+
+// zoneOffset returns the offset in seconds of a signed number that gives it
+// as hours, as HHMM, or as HH:MM when a colon follows.
+func (p *parser) zoneOffset(t token) int {
+	offset := t.value * 3600
+	if p.accept(":") {
+		if minutes := p.peek(0); minutes.kind == NUMBER && minutes.sign == 0 {
+			p.position++
+			offset += minutes.value * 60
+		}
+	} else if t.digits > 2 {
+		offset = t.value/100*3600 + t.value%100*60
+	}
+	return t.sign * offset
+}
@@ -0,0 +1,245 @@
+//
+// zone.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Michael Murphy
+//
+package parsedate
+
+import "bytes"
+import "encoding/binary"
+import "fmt"
+import "io/ioutil"
+import "os"
+import "strings"
+import "time"
+
+// LoadZone returns the location for a value of TZ: a zone name such as
+// "Europe/Paris", the path of a zoneinfo file, or a POSIX TZ string such as
+// "EST5EDT,M3.2.0,M11.1.0". A leading ':' is ignored, and an empty value is
+// UTC.
+func LoadZone(name string) (*time.Location, error) {
+	name = strings.TrimPrefix(name, ":")
+	switch {
+	case name == "":
+		return time.UTC, nil
+	case strings.HasPrefix(name, "/"):
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		return time.LoadLocationFromTZData(name, data)
+	}
+	if location, err := time.LoadLocation(name); err == nil {
+		return location, nil
+	}
+	return posixZone(name)
+}
+
+/* LocalZone returns the location that TZ names, or the system default if TZ
+ * is not set. A TZ that cannot be understood is taken, as the C library
+ * does, to be UTC with the value as its abbreviation. */
+func LocalZone() *time.Location {
+	name, ok := os.LookupEnv("TZ")
+	if !ok {
+		return time.Local
+	}
+	location, err := LoadZone(name)
+	if err != nil {
+		return time.FixedZone(name, 0)
+	}
+	return location
+}
+
+/* DescribeZone describes where the location came from, for debugging: the
+ * value of TZ it was loaded from, the system default if TZ is not set, or
+ * Universal Time if it is UTC whatever TZ says, as with date -u. */
+func DescribeZone(location *time.Location) string {
+	name, set := os.LookupEnv("TZ")
+	switch {
+	case location == time.Local:
+		return "system default"
+	case location == time.UTC && (!set || LocalZone() != time.UTC):
+		return "Universal Time"
+	case set:
+		return fmt.Sprintf(`TZ="%s" environment value`, name)
+	}
+	return fmt.Sprintf(`TZ="%s" environment value`, location)
+}
+
+// Returns the offset east of UTC as +hh, or +hh:mm or +hh:mm:ss where needed.
+func formatOffset(offset int) string {
+	sign := '+'
+	if offset < 0 {
+		sign, offset = '-', -offset
+	}
+	switch {
+	case offset%60 != 0:
+		return fmt.Sprintf("%c%02d:%02d:%02d", sign, offset/3600, offset/60%60, offset%60)
+	case offset%3600 != 0:
+		return fmt.Sprintf("%c%02d:%02d", sign, offset/3600, offset/60%60)
+	}
+	return fmt.Sprintf("%c%02d", sign, offset/3600)
+}
+
+/* posixZone returns the location for a POSIX TZ string,
+ *
+ *   std offset [dst [offset] [,start[/time],end[/time]]]
+ *
+ * The string is checked here and then handed to the time package as the
+ * footer of otherwise empty zoneinfo data, which applies its rules to every
+ * time. */
+func posixZone(spec string) (*time.Location, error) {
+	invalid := fmt.Errorf("invalid time zone '%s'", spec)
+	s := &posixScanner{spec: spec}
+	name, ok := s.name()
+	if !ok {
+		return nil, invalid
+	}
+	offset, ok := s.offset(24)
+	if !ok {
+		return nil, invalid
+	}
+	if !s.done() {
+		if _, ok := s.name(); !ok {
+			return nil, invalid
+		}
+		if !s.done() && s.spec[s.position] != ',' {
+			if _, ok := s.offset(24); !ok {
+				return nil, invalid
+			}
+		}
+		if !s.done() && !(s.accept(',') && s.rule() && s.accept(',') && s.rule() && s.done()) {
+			return nil, invalid
+		}
+	}
+	return time.LoadLocationFromTZData(spec, tzData(spec, name, -offset))
+}
+
+// A posixScanner reads the parts of a POSIX TZ string.
+type posixScanner struct {
+	spec     string
+	position int
+}
+
+// Returns true if the whole string has been read.
+func (s *posixScanner) done() bool {
+	return s.position == len(s.spec)
+}
+
+// Returns true, moving past it, if the next byte is c.
+func (s *posixScanner) accept(c byte) bool {
+	if !s.done() && s.spec[s.position] == c {
+		s.position++
+		return true
+	}
+	return false
+}
+
+// Reads a number of at most limit, returning false if there is none.
+func (s *posixScanner) number(limit int) (int, bool) {
+	start, value := s.position, 0
+	for !s.done() && isDigit(s.spec[s.position]) {
+		value = value*10 + int(s.spec[s.position]-'0')
+		s.position++
+	}
+	return value, s.position > start && value <= limit
+}
+
+// Reads a zone abbreviation: three or more letters, or anything in <>.
+func (s *posixScanner) name() (string, bool) {
+	start := s.position
+	if s.accept('<') {
+		end := strings.IndexByte(s.spec[start:], '>')
+		if end < 0 {
+			return "", false
+		}
+		s.position = start + end + 1
+		return s.spec[start+1 : start+end], end > 1
+	}
+	for !s.done() && isLetter(s.spec[s.position]) {
+		s.position++
+	}
+	return s.spec[start:s.position], s.position-start >= 3
+}
+
+// Reads [+-]hh[:mm[:ss]] as seconds, with hours of at most limit.
+func (s *posixScanner) offset(limit int) (int, bool) {
+	sign := 1
+	if s.accept('-') {
+		sign = -1
+	} else {
+		s.accept('+')
+	}
+	hours, ok := s.number(limit)
+	if !ok {
+		return 0, false
+	}
+	seconds := hours * 3600
+	for _, scale := range []int{60, 1} {
+		if !s.accept(':') {
+			break
+		}
+		value, ok := s.number(59)
+		if !ok {
+			return 0, false
+		}
+		seconds += value * scale
+	}
+	return sign * seconds, true
+}
+
+// Reads a transition rule, Jn, n or Mm.w.d, and its optional /time.
+func (s *posixScanner) rule() bool {
+	var ok bool
+	switch {
+	case s.accept('J'):
+		var day int
+		day, ok = s.number(365)
+		ok = ok && day >= 1
+	case s.accept('M'):
+		var month, week int
+		month, ok = s.number(12)
+		ok = ok && month >= 1 && s.accept('.')
+		if ok {
+			week, ok = s.number(5)
+			ok = ok && week >= 1 && s.accept('.')
+		}
+		if ok {
+			_, ok = s.number(6)
+		}
+	default:
+		_, ok = s.number(365)
+	}
+	if ok && s.accept('/') {
+		_, ok = s.offset(167)
+	}
+	return ok
+}
+
+/* tzData builds version 2 zoneinfo data with a single zone, the standard time
+ * of the POSIX TZ string, and the string itself as the footer. The version 1
+ * block that older readers use is left empty but for the zone. */
+func tzData(spec, name string, offset int) []byte {
+	var buffer bytes.Buffer
+	zone := func() {
+		binary.Write(&buffer, binary.BigEndian, int32(offset))
+		buffer.Write([]byte{0, 0}) // Not daylight saving, abbreviation at 0
+		buffer.WriteString(name + "\x00")
+	}
+	header := func() {
+		buffer.WriteString("TZif2")
+		buffer.Write(make([]byte, 15))
+		// isutcnt, isstdcnt, leapcnt, timecnt, typecnt, charcnt
+		for _, count := range []int{0, 0, 0, 0, 1, len(name) + 1} {
+			binary.Write(&buffer, binary.BigEndian, uint32(count))
+		}
+	}
+
+	header()
+	zone()
+	header()
+	zone()
+	buffer.WriteString("\n" + spec + "\n")
+	return buffer.Bytes()
+}
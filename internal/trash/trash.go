@@ -0,0 +1,435 @@
+//
+// trash.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Abram C. Isola
+//
+
+// Package trash moves files to the trash and back, following the
+// freedesktop.org Trash specification. It is shared by rm -trash and the
+// trash-list, trash-restore and trash-empty commands.
+package trash
+
+import "bufio"
+import "errors"
+import "fmt"
+import "net/url"
+import "os"
+import "path/filepath"
+import "sort"
+import "strings"
+import "syscall"
+import "time"
+
+import "github.com/aisola/go-coreutils/internal/filecopy"
+
+// The layout of the DeletionDate key, in local time.
+const DATE_FORMAT = "2006-01-02T15:04:05"
+
+/* A Trash is a trash directory, with files holding the trashed files and
+ * info holding a .trashinfo file for each. Top is the top directory of the
+ * mount the trash is for, to which the original paths are relative, or
+ * empty for the home trash, whose original paths are absolute. */
+type Trash struct {
+	Dir string
+	Top string
+}
+
+// An Item is a file in a trash and the path it was trashed from.
+type Item struct {
+	Trash   *Trash
+	Name    string
+	Path    string
+	Deleted time.Time
+
+	made time.Time // When the .trashinfo file was made, to order items trashed the same second
+}
+
+// Home returns the home trash, $XDG_DATA_HOME/Trash, which is
+// ~/.local/share/Trash when XDG_DATA_HOME is not set.
+func Home() (*Trash, error) {
+	data := os.Getenv("XDG_DATA_HOME")
+	if data == "" {
+		home := os.Getenv("HOME")
+		if home == "" {
+			return nil, errors.New("neither XDG_DATA_HOME nor HOME is set")
+		}
+		data = filepath.Join(home, ".local", "share")
+	}
+	return &Trash{Dir: filepath.Join(data, "Trash")}, nil
+}
+
+// Returns the path of the trashed file with the name.
+func (t *Trash) files(name string) string {
+	return filepath.Join(t.Dir, "files", name)
+}
+
+// Returns the path of the .trashinfo file of the trashed file.
+func (t *Trash) info(name string) string {
+	return filepath.Join(t.Dir, "info", name+".trashinfo")
+}
+
+/* create makes the files and info directories of the trash, and the trash
+ * itself, as needed. The trash of a mount lies in a directory others can
+ * write to, where another user could have put a symbolic link or their own
+ * directory in its place to be given the files, so it is made without
+ * following links and used only if it is private to the user. */
+func (t *Trash) create() error {
+	if t.Top == "" {
+		for _, directory := range []string{"files", "info"} {
+			if err := os.MkdirAll(filepath.Join(t.Dir, directory), 0700); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := os.Mkdir(t.Dir, 0700); err != nil && !os.IsExist(err) {
+		return err
+	}
+	if err := t.check(); err != nil {
+		return err
+	}
+	for _, directory := range []string{"files", "info"} {
+		if err := os.Mkdir(filepath.Join(t.Dir, directory), 0700); err != nil && !os.IsExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// check returns an error unless the trash of a mount is a real directory
+// that the user owns and no one else may use, as the specification requires.
+func (t *Trash) check() error {
+	var stat syscall.Stat_t
+	if err := syscall.Lstat(t.Dir, &stat); err != nil {
+		return &os.PathError{Op: "lstat", Path: t.Dir, Err: err}
+	}
+	switch {
+	case stat.Mode&syscall.S_IFMT != syscall.S_IFDIR:
+		return fmt.Errorf("trash '%s' is not a directory", t.Dir)
+	case int(stat.Uid) != os.Getuid():
+		return fmt.Errorf("trash '%s' is not owned by the user", t.Dir)
+	case stat.Mode&07777 != 0700:
+		return fmt.Errorf("trash '%s' has mode %04o rather than 0700", t.Dir, stat.Mode&07777)
+	}
+	return nil
+}
+
+// Returns the device of the file, which is not followed if it is a
+// symbolic link.
+func device(name string) (uint64, error) {
+	var stat syscall.Stat_t
+	if err := syscall.Lstat(name, &stat); err != nil {
+		return 0, &os.PathError{Op: "lstat", Path: name, Err: err}
+	}
+	return uint64(stat.Dev), nil
+}
+
+// Returns the top directory of the mount holding the path: the highest
+// directory above it on the same device.
+func topDirectory(path string, dev uint64) string {
+	for {
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		if parentDev, err := device(parent); err != nil || parentDev != dev {
+			return path
+		}
+		path = parent
+	}
+}
+
+/* topTrashes returns the trashes the mount whose top directory is top may
+ * have, in the order they are to be used. The shared $top/.Trash/$uid comes
+ * first, if $top/.Trash is a real directory with the sticky bit set, as the
+ * specification requires; then comes $top/.Trash-$uid. */
+func topTrashes(top string) []*Trash {
+	uid := fmt.Sprint(os.Getuid())
+	var trashes []*Trash
+	if info, err := os.Lstat(filepath.Join(top, ".Trash")); err == nil && info.IsDir() && info.Mode()&os.ModeSticky != 0 {
+		trashes = append(trashes, &Trash{Dir: filepath.Join(top, ".Trash", uid), Top: top})
+	}
+	return append(trashes, &Trash{Dir: filepath.Join(top, ".Trash-"+uid), Top: top})
+}
+
+/* For returns the trash to put the file at the absolute path in: the home
+ * trash if it is on the same device, else the trash of the file's mount.
+ * If that cannot be made, the home trash is used all the same, and the file
+ * is copied there. */
+func For(path string) (*Trash, error) {
+	home, err := Home()
+	if err != nil {
+		return nil, err
+	}
+	if err := home.create(); err != nil {
+		return nil, err
+	}
+	homeDev, err := device(home.Dir)
+	if err != nil {
+		return nil, err
+	}
+	dev, err := device(path)
+	if err != nil {
+		return nil, err
+	}
+	if dev == homeDev {
+		return home, nil
+	}
+	for _, trash := range topTrashes(topDirectory(path, dev)) {
+		if err := trash.create(); err == nil {
+			return trash, nil
+		}
+	}
+	return home, nil
+}
+
+// Returns the path, escaped as a URL path is but keeping its slashes.
+func escape(path string) string {
+	return (&url.URL{Path: path}).EscapedPath()
+}
+
+/* Put moves the file to the trash and returns the item it became. The
+ * .trashinfo file is made first, under a name no other item has, and is
+ * removed again if the file cannot be moved. A trash on another file system
+ * gets a copy of the file, as mv makes, and the original is removed. */
+func Put(name string) (Item, error) {
+	path, err := filepath.Abs(name)
+	if err != nil {
+		return Item{}, err
+	}
+	trash, err := For(path)
+	if err != nil {
+		return Item{}, err
+	}
+
+	original := path
+	if trash.Top != "" {
+		if relative, err := filepath.Rel(trash.Top, path); err == nil {
+			original = relative
+		}
+	}
+	item := Item{Trash: trash, Path: path, Deleted: time.Now()}
+	contents := fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+		escape(original), item.Deleted.Format(DATE_FORMAT))
+
+	base := filepath.Base(path)
+	for number := 1; ; number++ {
+		item.Name = base
+		if number > 1 {
+			item.Name = fmt.Sprintf("%s.%d", base, number)
+		}
+		info, err := os.OpenFile(trash.info(item.Name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return item, err
+		}
+		_, err = info.WriteString(contents)
+		if closeErr := info.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			if _, statErr := os.Lstat(trash.files(item.Name)); statErr == nil {
+				os.Remove(trash.info(item.Name))
+				continue // A file without an info file holds the name.
+			}
+			err = move(path, trash.files(item.Name))
+		}
+		if err != nil {
+			os.Remove(trash.info(item.Name))
+		}
+		return item, err
+	}
+}
+
+// move renames the file, or copies it and removes the original if the new
+// name is on another file system.
+func move(source, destination string) error {
+	err := os.Rename(source, destination)
+	if linkError, ok := err.(*os.LinkError); ok && linkError.Err == syscall.EXDEV {
+		return filecopy.Move(source, destination)
+	}
+	return err
+}
+
+/* All returns the trashes there are: the home trash and the trash of each
+ * mount that has one of the user's own. Where /proc/self/mounts cannot be
+ * read, as on systems other than Linux, only the home trash is known. */
+func All() ([]*Trash, error) {
+	home, err := Home()
+	if err != nil {
+		return nil, err
+	}
+	trashes := []*Trash{home}
+
+	mounts, err := os.Open("/proc/self/mounts")
+	if err != nil {
+		return trashes, nil
+	}
+	defer mounts.Close()
+	seen := map[string]bool{}
+	scanner := bufio.NewScanner(mounts)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || seen[fields[1]] {
+			continue
+		}
+		top := unescapeMount(fields[1])
+		seen[fields[1]] = true
+		for _, trash := range topTrashes(top) {
+			if trash.Dir == home.Dir || trash.check() != nil {
+				continue
+			}
+			if info, err := os.Lstat(filepath.Join(trash.Dir, "info")); err == nil && info.IsDir() {
+				trashes = append(trashes, trash)
+			}
+		}
+	}
+	return trashes, nil
+}
+
+// Returns the mount point with the octal escapes of /proc/self/mounts, as
+// in \040 for a space, undone.
+func unescapeMount(field string) string {
+	var result []byte
+	for index := 0; index < len(field); index++ {
+		if field[index] == '\\' && index+3 < len(field) {
+			var value byte
+			if _, err := fmt.Sscanf(field[index+1:index+4], "%03o", &value); err == nil {
+				result = append(result, value)
+				index += 3
+				continue
+			}
+		}
+		result = append(result, field[index])
+	}
+	return string(result)
+}
+
+// Items returns the items of the trash, oldest first. Info files without a
+// trashed file, or that cannot be read, are left out.
+func (t *Trash) Items() ([]Item, error) {
+	directory, err := os.Open(filepath.Join(t.Dir, "info"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	names, err := directory.Readdirnames(-1)
+	directory.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var items []Item
+	for _, name := range names {
+		if !strings.HasSuffix(name, ".trashinfo") {
+			continue
+		}
+		item := Item{Trash: t, Name: strings.TrimSuffix(name, ".trashinfo")}
+		if _, err := os.Lstat(t.files(item.Name)); err != nil {
+			continue
+		}
+		if err := item.readInfo(); err == nil {
+			items = append(items, item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].before(items[j]) })
+	return items, nil
+}
+
+// List returns the items of every trash, oldest first.
+func List() ([]Item, error) {
+	trashes, err := All()
+	if err != nil {
+		return nil, err
+	}
+	var items []Item
+	for _, trash := range trashes {
+		some, err := trash.Items()
+		if err != nil {
+			return items, err
+		}
+		items = append(items, some...)
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].before(items[j]) })
+	return items, nil
+}
+
+// Returns true if the item was trashed before the other.
+func (i Item) before(other Item) bool {
+	if !i.Deleted.Equal(other.Deleted) {
+		return i.Deleted.Before(other.Deleted)
+	}
+	return i.made.Before(other.made)
+}
+
+// readInfo reads the original path and deletion date of the item from its
+// .trashinfo file.
+func (i *Item) readInfo() error {
+	file, err := os.Open(i.Trash.info(i.Name))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if info, err := file.Stat(); err == nil {
+		i.made = info.ModTime()
+	}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "Path="):
+			path, err := url.PathUnescape(strings.TrimPrefix(line, "Path="))
+			if err != nil {
+				return err
+			}
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(i.Trash.Top, path)
+			}
+			i.Path = path
+		case strings.HasPrefix(line, "DeletionDate="):
+			date := strings.TrimPrefix(line, "DeletionDate=")
+			if deleted, err := time.ParseInLocation(DATE_FORMAT, date, time.Local); err == nil {
+				i.Deleted = deleted
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if i.Path == "" {
+		return fmt.Errorf("%s: no Path key", i.Trash.info(i.Name))
+	}
+	return nil
+}
+
+/* Restore moves the item back to the path it was trashed from, making the
+ * directories above it if they have gone, and removes its .trashinfo file.
+ * A file that has since taken the path is not replaced. */
+func (i Item) Restore() error {
+	if _, err := os.Lstat(i.Path); err == nil {
+		return &os.PathError{Op: "restore", Path: i.Path, Err: syscall.EEXIST}
+	}
+	if err := os.MkdirAll(filepath.Dir(i.Path), 0777); err != nil {
+		return err
+	}
+	if err := move(i.Trash.files(i.Name), i.Path); err != nil {
+		return err
+	}
+	return os.Remove(i.Trash.info(i.Name))
+}
+
+// Remove deletes the item from the trash for good: the trashed file first,
+// and then its .trashinfo file.
+func (i Item) Remove() error {
+	if err := os.RemoveAll(i.Trash.files(i.Name)); err != nil {
+		return err
+	}
+	return os.Remove(i.Trash.info(i.Name))
+}
@@ -0,0 +1,22 @@
+//
+// context_linux.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Corey Prak
+//
+
+// +build linux
+
+package main
+
+import "syscall"
+
+// setContext sets the SELinux security context of the file, unless the
+// file system cannot hold one.
+func setContext(name, context string) error {
+	err := syscall.Setxattr(name, "security.selinux", []byte(context+"\x00"), 0)
+	if err == syscall.ENOTSUP {
+		return nil
+	}
+	return err
+}
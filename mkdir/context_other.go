@@ -0,0 +1,15 @@
+//
+// context_other.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Corey Prak
+//
+
+// +build !linux
+
+package main
+
+// setContext does nothing, as there is no SELinux to set the context of.
+func setContext(name, context string) error {
+	return nil
+}
@@ -11,17 +11,31 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/aisola/go-coreutils/internal/mode"
 )
 
 const (
 	help_text string = `
-    Usage: mkdir OPTION(S) DIRECTORY
+    Usage: mkdir [OPTION]... DIRECTORY...
+
+    Create the DIRECTORY(ies), if they do not already exist.
 
-        --help        display this help and exit
-        --version     output version information and exit
-        --parents     create parent directory/directories as
-                      needed, do nothing if already existing
-        --verbose     print a message for each created directory
+        -help         display this help and exit
+        -version      output version information and exit
+        -m, -mode=MODE
+                      set file mode (as in chmod), not a=rwx - umask
+        -p, -parents  no error if existing, make parent directories as
+                      needed, with their file modes unaffected by any -m
+                      option
+        -v, -verbose  print a message for each created directory
+        -Z            set SELinux security context of each created
+                      directory to the default type
+        -context[=CTX]
+                      like -Z, or if CTX is specified then set the SELinux
+                      security context to CTX
   `
 
 	version_text = `
@@ -38,62 +52,167 @@ const (
     already exist, do nothing.
   `
 
-	usage_text = `usage: mkdir [-parents, -verbose] directory ...`
-
 	verbose_text = `Print a message for each created directory.`
 )
 
+// A contextValue is the -context flag, whose CTX may be left out; set
+// records whether the flag was given at all.
+type contextValue struct {
+	set   bool
+	value string
+}
+
+func (c *contextValue) String() string   { return c.value }
+func (c *contextValue) IsBoolFlag() bool { return true }
+
+func (c *contextValue) Set(value string) error {
+	c.set = true
+	if value == "true" { // The flag was given without a value
+		value = ""
+	}
+	c.value = value
+	return nil
+}
+
 var (
-	help    = flag.Bool("help", false, help_text)
-	version = flag.Bool("version", false, version_text)
-	parents = flag.Bool("parents", false, parents_text)
-	verbose = flag.Bool("verbose", false, verbose_text)
+	help           = flag.Bool("help", false, help_text)
+	version        = flag.Bool("version", false, version_text)
+	parents        = flag.Bool("p", false, parents_text)
+	parentsLong    = flag.Bool("parents", false, parents_text)
+	verbose        = flag.Bool("v", false, verbose_text)
+	verboseLong    = flag.Bool("verbose", false, verbose_text)
+	modeString     = flag.String("m", "", "set file mode (as in chmod), not a=rwx - umask")
+	modeStringLong = flag.String("mode", "", "set file mode (as in chmod), not a=rwx - umask")
+	defaultContext = flag.Bool("Z", false, "set SELinux security context of each created directory to the default type")
+
+	context contextValue
 )
 
-func extend(slice []string, element string) []string {
-	if len(slice) == cap(slice) {
-		newSlice := make([]string, len(slice), 2*cap(slice))
-		copy(newSlice, slice)
-		slice = newSlice
+// The mode of the directories named, the bits of it that -m sets, and the
+// umask it was worked out with.
+var dirMode uint32 = 0777
+var modeBits uint32
+var umask = mode.Umask()
+
+// Returns true if the kernel enforces SELinux, which keeps its file system
+// mounted at /sys/fs/selinux.
+func selinuxEnabled() bool {
+	_, err := os.Stat("/sys/fs/selinux/enforce")
+	return err == nil
+}
+
+// Returns the error's underlying message, capitalised as the C library's
+// messages are.
+func errorString(err error) string {
+	if pathError, ok := err.(*os.PathError); ok {
+		err = pathError.Err
 	}
-	n := len(slice)
-	slice = slice[0 : n+1]
-	slice[n] = element
-	return slice
+	message := err.Error()
+	return strings.ToUpper(message[:1]) + message[1:]
 }
 
-func getAllPaths(dir string) []string {
-	slice := make([]string, 0, 10)
-	i := 0
-	_, err := os.Stat(dir)
-	for err != nil {
-		if dir == "." || dir == "/" {
+/* makeDirectory creates the directory with the mode, and with the security
+ * context of -context. The umask has already been applied to the mode and
+ * is cleared while mkdir runs. The kernel does not set the setuid and
+ * setgid bits, so if the bits of -m touch them the directory is made
+ * without write access for others and then changed with chmod, as GNU
+ * does, leaving alone the bits that -m does not mention. */
+func makeDirectory(dir string, perm, bits uint32) error {
+	create := perm
+	if bits&(mode.S_ISUID|mode.S_ISGID) != 0 || perm&mode.S_ISVTX != 0 {
+		create &^= 022
+	}
+	if err := syscall.Mkdir(dir, create&01777); err != nil {
+		return err
+	}
+	if bits != 0 {
+		var stat syscall.Stat_t
+		err := syscall.Stat(dir, &stat)
+		if err == nil && (perm^uint32(stat.Mode))&bits != 0 {
+			err = syscall.Chmod(dir, perm|uint32(stat.Mode)&mode.ALL_BITS&^bits)
+		}
+		if err != nil {
+			return fmt.Errorf("cannot change permissions of '%s': %s", dir, errorString(err))
+		}
+	}
+	if context.value != "" {
+		if err := setContext(dir, context.value); err != nil {
+			return fmt.Errorf("failed to set the security context of '%s' to '%s': %s",
+				dir, context.value, errorString(err))
+		}
+	}
+	if *verbose {
+		fmt.Printf("mkdir: created directory '%s'\n", dir)
+	}
+	return nil
+}
+
+// Returns the paths of the directories above dir, outermost first, that
+// -parents has to make.
+func missingParents(dir string) []string {
+	var missing []string
+	for parent := filepath.Dir(dir); parent != "." && parent != "/"; parent = filepath.Dir(parent) {
+		if _, err := os.Lstat(parent); err == nil {
 			break
 		}
-		slice = extend(slice, dir)
-		i += 1
-		dir = filepath.Dir(dir)
-		_, err = os.Stat(dir)
+		missing = append([]string{parent}, missing...)
 	}
-	return slice
+	return missing
 }
 
-func printAllPaths(slice []string) {
-	i := len(slice)
-	for i > 0 {
-		i -= 1
-		fmt.Printf("mkdir: created directory `%s'\n", slice[i])
+/* makeParents makes the directory and any missing directories above it.
+ * As in GNU, the directories above get a=rwx less the umask, plus u+wx so
+ * that the ones below can be made, and only the last gets the -m mode. An
+ * existing directory is not an error. */
+func makeParents(dir string) error {
+	parentMode := 0777&^umask | 0300
+	for _, parent := range missingParents(dir) {
+		err := makeDirectory(parent, parentMode, 0)
+		if err != nil && err != syscall.EEXIST {
+			return fmt.Errorf("cannot create directory '%s': %s", parent, errorString(err))
+		}
+	}
 
+	err := makeDirectory(dir, dirMode, modeBits)
+	if err == syscall.EEXIST {
+		if info, statErr := os.Stat(dir); statErr == nil && info.IsDir() {
+			return nil
+		}
+	}
+	if errno, ok := err.(syscall.Errno); ok {
+		return fmt.Errorf("cannot create directory '%s': %s", dir, errorString(errno))
 	}
+	return err
 }
 
 func main() {
-	flag.Parse()
-
 	if flag.NArg() == 0 {
-		fmt.Println(usage_text)
-		os.Exit(0)
+		fmt.Fprintln(os.Stderr, "mkdir: missing operand")
+		fmt.Fprintln(os.Stderr, "Try 'mkdir -help' for more information.")
+		os.Exit(1)
+	}
+
+	status := 0
+	for _, dir := range flag.Args() {
+		var err error
+		if *parents {
+			err = makeParents(dir)
+		} else if err = makeDirectory(dir, dirMode, modeBits); err != nil {
+			if errno, ok := err.(syscall.Errno); ok {
+				err = fmt.Errorf("cannot create directory '%s': %s", dir, errorString(errno))
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "mkdir: %s\n", err)
+			status = 1
+		}
 	}
+	os.Exit(status)
+}
+
+func init() {
+	flag.Var(&context, "context", "set the SELinux security context of each created directory to CTX")
+	flag.Parse()
 
 	if *help {
 		fmt.Println(help_text)
@@ -105,26 +224,34 @@ func main() {
 		os.Exit(0)
 	}
 
-	for i := 0; i < flag.NArg(); i++ {
-
-		if *parents {
-			paths := getAllPaths(flag.Arg(i))
-			mkdirAllError := os.MkdirAll(flag.Arg(i), os.ModePerm)
-
-			if mkdirAllError != nil {
-				fmt.Println(mkdirAllError)
-			} else if *verbose {
-				printAllPaths(paths)
-			}
-		} else {
-			mkdirError := os.Mkdir(flag.Arg(i), os.ModePerm)
+	if *parentsLong {
+		*parents = true
+	}
+	if *verboseLong {
+		*verbose = true
+	}
+	if *modeStringLong != "" {
+		*modeString = *modeStringLong
+	}
 
-			if mkdirError != nil {
-				fmt.Println(mkdirError)
-			} else if *verbose {
-				fmt.Printf("mkdir: created directory '%s'\n", flag.Arg(i))
-			}
+	if *modeString != "" {
+		changes, err := mode.Parse(*modeString)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "mkdir: %s\n", err)
+			os.Exit(1)
 		}
+		dirMode, modeBits = changes.Apply(0777, true, umask)
+	} else {
+		dirMode = 0777 &^ umask
+	}
+	syscall.Umask(0)
 
+	// Without SELinux there is no context to set, as in GNU. -Z, and
+	// -context without CTX, leave the default context the kernel gives.
+	if (*defaultContext || context.set) && !selinuxEnabled() {
+		if context.value != "" {
+			fmt.Fprintln(os.Stderr, "mkdir: warning: ignoring --context; it requires an SELinux-enabled kernel")
+		}
+		context.value = ""
 	}
 }
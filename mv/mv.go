@@ -4,28 +4,74 @@
 //
 // Written By: Abram C. Isola, Michael Murphy
 //
+
 package main
 
 import "bufio"
 import "flag"
 import "fmt"
-import "io"
 import "os"
 import "path/filepath"
+import "strings"
+import "syscall"
+
+import "github.com/aisola/go-coreutils/internal/backup"
+import "github.com/aisola/go-coreutils/internal/cli"
+import "github.com/aisola/go-coreutils/internal/filecopy"
 
 const (
 	help_text string = `
-    Usage: mv [OPTION]... [PATH]... [PATH]
-       or: mv [PATH] [PATH]
-       or: mv [OPTION]
-
-    move or rename files or directories
-
-        --help        display this help and exit
-        --version     output version information and exit
-
-        -f, --force   remove existing destination files and never prompt the user
-    ` // -v, --verbose print the name of each file before moving it
+    Usage: mv [OPTION]... [-T] SOURCE DEST
+       or: mv [OPTION]... SOURCE... DIRECTORY
+       or: mv [OPTION]... -t DIRECTORY SOURCE...
+
+    Rename SOURCE to DEST, or move SOURCE(s) to DIRECTORY.
+
+        -help         display this help and exit
+        -version      output version information and exit
+
+        -backup[=CONTROL]
+                      make a backup of each existing destination file
+        -b            like -backup but does not accept an argument
+        -exchange     exchange SOURCE and DEST atomically
+        -f, -force    do not prompt before overwriting
+        -i, -interactive
+                      prompt before overwrite
+        -n, -no-clobber
+                      do not overwrite an existing file
+
+        If you specify more than one of -i, -f, -n, only the final one takes
+        effect.
+
+        -strip-trailing-slashes
+                      remove any trailing slashes from each SOURCE argument
+        -S, -suffix=SUFFIX
+                      override the usual backup suffix
+        -t, -target-directory=DIRECTORY
+                      move all SOURCE arguments into DIRECTORY
+        -T, -no-target-directory
+                      treat DEST as a normal file
+        -update[=UPDATE]
+                      control which existing files are updated;
+                      UPDATE={all,none,older(default)}
+        -u            equivalent to -update[=older]
+        -v, -verbose  explain what is being done
+
+    With -exchange, each SOURCE and its destination must both exist, and
+    they swap names in one step. With -n, an existing destination is never
+    replaced, even one created while mv runs. Either fails, rather than
+    falling back to an ordinary rename, on a file system that cannot rename
+    in these ways.
+
+    The backup suffix is '~', unless set with -suffix or SIMPLE_BACKUP_SUFFIX.
+    The version control method may be selected via the -backup option or
+    through the VERSION_CONTROL environment variable. Here are the values:
+
+        none, off       never make backups (even if -backup is given)
+        numbered, t     make numbered backups
+        existing, nil   numbered if numbered backups exist, simple otherwise
+        simple, never   always make simple backups
+`
 	version_text = `
     mv (go-coreutils) 0.1
 
@@ -37,174 +83,354 @@ const (
 )
 
 var (
-	forceEnabled     = flag.Bool("f", false, "remove existing destination files and never prompt the user")
-	forceEnabledLong = flag.Bool("force", false, "remove existing destination files and never prompt the user")
+	forceEnabled          = flag.Bool("f", false, "do not prompt before overwriting")
+	forceEnabledLong      = flag.Bool("force", false, "do not prompt before overwriting")
+	interactive           = flag.Bool("i", false, "prompt before overwrite")
+	interactiveLong       = flag.Bool("interactive", false, "prompt before overwrite")
+	noClobber             = flag.Bool("n", false, "do not overwrite an existing file")
+	noClobberLong         = flag.Bool("no-clobber", false, "do not overwrite an existing file")
+	updateOlder           = flag.Bool("u", false, "move only when the SOURCE file is newer than the destination file")
+	makeBackup            = flag.Bool("b", false, "like -backup but does not accept an argument")
+	exchange              = flag.Bool("exchange", false, "exchange SOURCE and DEST atomically")
+	suffix                = flag.String("S", "", "override the usual backup suffix")
+	suffixLong            = flag.String("suffix", "", "override the usual backup suffix")
+	targetDirectory       = flag.String("t", "", "move all SOURCE arguments into DIRECTORY")
+	targetDirectoryLong   = flag.String("target-directory", "", "move all SOURCE arguments into DIRECTORY")
+	noTargetDirectory     = flag.Bool("T", false, "treat DEST as a normal file")
+	noTargetDirectoryLong = flag.Bool("no-target-directory", false, "treat DEST as a normal file")
+	verbose               = flag.Bool("v", false, "explain what is being done")
+	verboseLong           = flag.Bool("verbose", false, "explain what is being done")
+	stripTrailingSlashes  = flag.Bool("strip-trailing-slashes", false, "remove any trailing slashes from each SOURCE argument")
+	help                  = flag.Bool("help", false, "display help information")
+	version               = flag.Bool("version", false, "display version information")
+
+	backupControl cli.OptionalValue
+	update        cli.OptionalValue
 )
 
-// The input function prints a statement to the user and accepts an input, then returns the input.
+// How an existing destination is treated, set by the last of -f, -i and -n.
+const (
+	OVERWRITE_DEFAULT = iota // Prompt only for a destination that is not writable
+	OVERWRITE_FORCE          // Never prompt
+	OVERWRITE_PROMPT         // Always prompt
+	OVERWRITE_NEVER          // Never overwrite
+)
 
-func input(prompt, location string) string {
-	fmt.Printf(prompt, location)
+var overwriteMode = OVERWRITE_DEFAULT
+var backupType = backup.NONE
+
+// The flags that take their value from the following argument.
+var valueFlags = map[string]bool{"S": true, "suffix": true, "t": true, "target-directory": true}
+
+/* getOverwriteMode returns the mode of the last of -f, -i and -n on the
+ * command line, as each overrides those before it. */
+func getOverwriteMode() int {
+	mode := OVERWRITE_DEFAULT
+	for _, given := range cli.GivenFlags(os.Args[1:], valueFlags) {
+		switch given.Name {
+		case "f", "force":
+			mode = OVERWRITE_FORCE
+		case "i", "interactive":
+			mode = OVERWRITE_PROMPT
+		case "n", "no-clobber":
+			mode = OVERWRITE_NEVER
+		}
+	}
+	return mode
+}
 
-	reader := bufio.NewReader(os.Stdin)
-	userinput, _ := reader.ReadString([]byte("\n")[0])
+// The answers to prompts, read a line at a time.
+var stdin = bufio.NewReader(os.Stdin)
 
-	return userinput
+// The input function prints a prompt to the user on standard error and
+// returns true if the answer is yes.
+func input(format string, a ...interface{}) bool {
+	fmt.Fprintf(os.Stderr, "mv: "+format, a...)
+	answer, _ := stdin.ReadString('\n')
+	return strings.HasPrefix(answer, "y") || strings.HasPrefix(answer, "Y")
 }
 
-// The fileExists function will check if the file exists.
+// Returns the permissions of the mode as ls shows them, as in rw-r--r--.
+func permissionString(mode os.FileMode) string {
+	return mode.Perm().String()[1:]
+}
 
-func fileExists(filep string) os.FileInfo {
-	fp, err := os.Stat(filep)
-	if err != nil && os.IsNotExist(err) {
-		return nil
+/* shouldReplace decides whether the existing destination is replaced, as
+ * -n, -update, -i and -f ask, prompting the user where needed. */
+func shouldReplace(source os.FileInfo, destination string, destinationInfo os.FileInfo) bool {
+	switch {
+	case overwriteMode == OVERWRITE_NEVER:
+		return false
+	case update.Value == "none":
+		return false
+	case update.Value == "older" && !source.ModTime().After(destinationInfo.ModTime()):
+		return false
+	case overwriteMode == OVERWRITE_PROMPT:
+		return input("overwrite '%s'? ", destination)
+	case overwriteMode == OVERWRITE_DEFAULT && cli.IsTerminal() &&
+		destinationInfo.Mode()&os.ModeSymlink == 0 && !isWritable(destination):
+		mode := destinationInfo.Mode()
+		return input("replace '%s', overriding mode %04o (%s)? ", destination, mode.Perm(), permissionString(mode))
 	}
-	return fp
+	return true
 }
 
-/* The argumentCheck function will check the number of arguments given to the program and process them
- * accordingly. */
+/* The exchanger function swaps the source and the destination, which must
+ * both exist, in one step. Neither is lost, so nothing is prompted for or
+ * backed up. */
+func exchanger(originalLocation, newLocation string) error {
+	source, err := os.Lstat(originalLocation)
+	if err != nil {
+		return fmt.Errorf("cannot stat '%s': %s", originalLocation, cli.ErrorString(err))
+	}
+	destination, err := os.Lstat(newLocation)
+	if err != nil {
+		return fmt.Errorf("cannot stat '%s': %s", newLocation, cli.ErrorString(err))
+	}
+	if os.SameFile(source, destination) {
+		return fmt.Errorf("'%s' and '%s' are the same file", originalLocation, newLocation)
+	}
 
-func argumentCheck(files []string) {
-	switch len(files) {
-	case 0: // If there is no argument
-		fmt.Println("mv: missing file operand\nTry 'mv -help' for more information")
-		os.Exit(0)
-	case 1: // If there is one argument
-		fmt.Printf("mv: missing destination file operand after '%s'\nTry 'mv -help' for more information.\n", files[0])
-		os.Exit(0)
-	case 2: // If there are two arguments
-		mover(files[0], files[1])
-	default: // If there are more than two arguments
-		to_file, files := files[len(files)-1], files[:len(files)-1]
-
-		if fp := fileExists(to_file); fp == nil || !fp.IsDir() {
-			fmt.Println("mv: when moving multiple files, last argument must be a directory")
-			os.Exit(0)
-		} else {
-			fmt.Println(files)
-			for i := 0; i < len(files); i++ {
-				mover(files[i], to_file)
-			}
-			os.Exit(0)
-		}
+	if err := renameat2(originalLocation, newLocation, RENAME_EXCHANGE); err != nil {
+		return fmt.Errorf("cannot exchange '%s' and '%s': %s", originalLocation, newLocation, cli.ErrorString(err))
 	}
+	if *verbose {
+		fmt.Printf("exchanged '%s' <-> '%s'\n", originalLocation, newLocation)
+	}
+	return nil
 }
 
-/* The mover function will take two strings as an argument and move the original file/dir to
- * a new location. */
+/* The mover function moves the source to the destination, which is taken as
+ * the final name of the source. It returns the error to report, if any; a
+ * destination that is not to be replaced is skipped without one. */
+func mover(originalLocation, newLocation string) error {
+	source, err := os.Lstat(originalLocation)
+	if err != nil {
+		return fmt.Errorf("cannot stat '%s': %s", originalLocation, cli.ErrorString(err))
+	}
 
-func mover(originalLocation, newLocation string) {
-	fp := fileExists(newLocation)
+	if source.IsDir() && cli.IsInside(newLocation, originalLocation) {
+		return fmt.Errorf("cannot move '%s' to a subdirectory of itself, '%s'", originalLocation, newLocation)
+	}
 
-	switch {
-	case fileExists(originalLocation) == nil: // If the original file does not exist
-		fmt.Printf("mv: cannot stat '%s': No such file or directory\n", originalLocation)
-		os.Exit(0)
-	case fp != nil && !*forceEnabled: // If the destination file does not exist and forceEnabled is disabled
-		if fp.IsDir() {
-			base := filepath.Base(originalLocation)
-			if fp2 := fileExists(newLocation + "/" + base); fp2 != nil && !*forceEnabled {
-				answer := input("File '%s' exists. Overwrite? (y/N): ", newLocation+"/"+base)
-				if answer == "y\n" {
-					try_move(originalLocation, newLocation+"/"+base)
-				} else {
-					os.Exit(0)
-				}
-			} else if fp2 != nil && *forceEnabled {
-				try_move(originalLocation, newLocation+"/"+base)
-			} else if fp2 == nil {
-				try_move(originalLocation, newLocation+"/"+base)
-			}
-		} else {
-			answer := input("File '%s' exists. Overwrite? (y/N): ", newLocation)
-			if answer == "y\n" {
-				try_move(originalLocation, newLocation)
-			} else {
-				os.Exit(0)
+	backupName := ""
+	if destination, err := os.Lstat(newLocation); err == nil {
+		switch {
+		case os.SameFile(source, destination):
+			return fmt.Errorf("'%s' and '%s' are the same file", originalLocation, newLocation)
+		case source.IsDir() && !destination.IsDir():
+			return fmt.Errorf("cannot overwrite non-directory '%s' with directory '%s'", newLocation, originalLocation)
+		case !source.IsDir() && destination.IsDir():
+			return fmt.Errorf("cannot overwrite directory '%s' with non-directory", newLocation)
+		case !shouldReplace(source, newLocation, destination):
+			return nil
+		}
+
+		if backupName = backup.Name(newLocation, backupType, backup.Suffix(*suffix)); backupName != "" {
+			if err := os.Rename(newLocation, backupName); err != nil {
+				return fmt.Errorf("cannot backup '%s': %s", newLocation, cli.ErrorString(err))
 			}
 		}
-	default: // If the destination file exists and forceEnabled is enabled,
-		try_move(originalLocation, newLocation) // or if the file does not exist, move it.
 	}
-}
 
-func try_move(originalLocation, newLocation string) error {
-	err := os.Rename(originalLocation, newLocation)
-	switch t := err.(type) {
-	case *os.LinkError:
-		fmt.Printf("Cross-device move. Copying instead\n")
-		return move_across_devices(originalLocation, newLocation)
-	case *os.PathError:
-		fmt.Printf("Path error: %q\n", t)
-		return err
-	case *os.SyscallError:
-		fmt.Printf("Syscall error: %q\n", t)
-		return err
-	case nil:
-		return nil
-	default:
-		fmt.Printf("Unkown error Type: %T Error: %q", t, t)
-		return err
+	if err := try_move(originalLocation, newLocation); err != nil {
+		if isReplaceRefused(err, newLocation) {
+			return nil // The destination appeared since it was checked.
+		}
+		if backupName != "" {
+			os.Rename(backupName, newLocation)
+		}
+		return fmt.Errorf("cannot move '%s' to '%s': %s", originalLocation, newLocation, cli.ErrorString(err))
+	}
+
+	if *verbose && backupName != "" {
+		fmt.Printf("renamed '%s' -> '%s' (backup: '%s')\n", originalLocation, newLocation, backupName)
+	} else if *verbose {
+		fmt.Printf("renamed '%s' -> '%s'\n", originalLocation, newLocation)
 	}
 	return nil
 }
 
-func move_across_devices(originalLocation, newLocation string) error {
-	src, err := os.Open(originalLocation)
+// Returns true if the current user may write to the file.
+func isWritable(name string) bool {
+	file, err := os.OpenFile(name, os.O_WRONLY, 0)
 	if err != nil {
-		return err
+		return !os.IsPermission(err)
 	}
-	defer src.Close()
+	file.Close()
+	return true
+}
 
-	dst, err := os.Create(newLocation)
-	if err != nil {
-		return err
+/* The argumentCheck function checks the operands and moves each source,
+ * returning the exit status. Errors are reported as they happen, and the
+ * remaining sources are still moved. */
+func argumentCheck(files []string) int {
+	if *stripTrailingSlashes {
+		stripped := files
+		if *targetDirectory == "" && len(files) > 1 {
+			stripped = files[:len(files)-1] // The destination keeps its slashes.
+		}
+		for index, file := range stripped {
+			if trimmed := strings.TrimRight(file, "/"); trimmed != "" {
+				stripped[index] = trimmed
+			}
+		}
 	}
-	defer dst.Close()
 
-	size, err := io.Copy(dst, src)
-	if err != nil {
-		return err
+	move := mover
+	if *exchange {
+		move = exchanger
 	}
 
-	srcStat, err := os.Stat(originalLocation)
-	if err != nil {
-		return err
+	var sources []string
+	var directory string
+	switch {
+	case *targetDirectory != "" && *noTargetDirectory:
+		fmt.Fprintln(os.Stderr, "mv: cannot combine --target-directory (-t) and --no-target-directory (-T)")
+		return 1
+	case len(files) == 0:
+		cli.UsageError("mv", "missing file operand")
+	case *targetDirectory != "":
+		if info, err := os.Stat(*targetDirectory); err != nil {
+			fmt.Fprintf(os.Stderr, "mv: target directory '%s': %s\n", *targetDirectory, cli.ErrorString(err))
+			return 1
+		} else if !info.IsDir() {
+			fmt.Fprintf(os.Stderr, "mv: target directory '%s': Not a directory\n", *targetDirectory)
+			return 1
+		}
+		sources, directory = files, *targetDirectory
+	case len(files) == 1:
+		cli.UsageError("mv", "missing destination file operand after '%s'", files[0])
+	case *noTargetDirectory && len(files) > 2:
+		cli.UsageError("mv", "extra operand '%s'", files[2])
+	case *noTargetDirectory || (len(files) == 2 && (*exchange || !cli.IsDirectory(files[1]))):
+		return report(move(files[0], files[1]))
+	default:
+		sources, directory = files[:len(files)-1], files[len(files)-1]
+		if info, err := os.Stat(directory); err != nil {
+			fmt.Fprintf(os.Stderr, "mv: target '%s': %s\n", directory, cli.ErrorString(err))
+			return 1
+		} else if !info.IsDir() {
+			fmt.Fprintf(os.Stderr, "mv: target '%s' is not a directory\n", directory)
+			return 1
+		}
 	}
-	if size != srcStat.Size() {
-		os.Remove(newLocation)
-		return fmt.Errorf("Error, file was not copied completely")
+
+	status := 0
+	for _, source := range sources {
+		if report(move(source, filepath.Join(directory, filepath.Base(source)))) != 0 {
+			status = 1
+		}
 	}
-	os.Remove(originalLocation)
-	return nil
+	return status
 }
 
-func main() {
-	help := flag.Bool("help", false, help_text)
-	version := flag.Bool("version", false, version_text)
-	flag.Parse()
+// report prints the error, if any, and returns the exit status for it.
+func report(err error) int {
+	if err == nil {
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "mv: %s\n", err)
+	return 1
+}
 
-	// We only need one instance of forceEnabled
+// Returns true if existing destinations must never be replaced.
+func noReplace() bool {
+	return overwriteMode == OVERWRITE_NEVER || update.Value == "none"
+}
 
-	if *forceEnabledLong {
-		*forceEnabled = true
+// Returns true if the error is a no-replace rename refusing to replace the
+// destination.
+func isReplaceRefused(err error, newLocation string) bool {
+	linkError, ok := err.(*os.LinkError)
+	return ok && noReplace() && linkError.Err == syscall.EEXIST && linkError.New == newLocation
+}
+
+// rename renames the file, without replacing the destination if -n asks so.
+func rename(originalLocation, newLocation string) error {
+	if noReplace() {
+		return renameat2(originalLocation, newLocation, RENAME_NOREPLACE)
+	}
+	return os.Rename(originalLocation, newLocation)
+}
+
+func try_move(originalLocation, newLocation string) error {
+	err := rename(originalLocation, newLocation)
+	if linkError, ok := err.(*os.LinkError); ok && linkError.Err == syscall.EXDEV {
+		return move_across_devices(originalLocation, newLocation)
 	}
+	return err
+}
 
-	// Display help information
+/* move_across_devices moves the file or directory tree to another file
+ * system by copying it, with its metadata, and then removing the original,
+ * which is left untouched if the copy fails. */
+func move_across_devices(originalLocation, newLocation string) error {
+	return filecopy.MoveWith(originalLocation, newLocation, rename)
+}
+
+func main() {
+	files := flag.Args() // Obtain a list of files.
+	os.Exit(argumentCheck(files))
+}
+
+func init() {
+	flag.Var(&backupControl, "backup", "make a backup of each existing destination file")
+	flag.Var(&update, "update", "control which existing files are updated: all, none or older")
+	flag.Parse()
 
 	if *help {
-		fmt.Println(help_text)
+		fmt.Print(help_text)
 		os.Exit(0)
 	}
-
-	// Display version information
-
 	if *version {
-		fmt.Println(version_text)
+		fmt.Print(version_text)
 		os.Exit(0)
 	}
 
-	files := flag.Args() // Obtain a list of files.
-	argumentCheck(files) // Check the number of arguments and process them.
+	if *forceEnabledLong {
+		*forceEnabled = true
+	}
+	if *interactiveLong {
+		*interactive = true
+	}
+	if *noClobberLong {
+		*noClobber = true
+	}
+	if *suffixLong != "" {
+		*suffix = *suffixLong
+	}
+	if *targetDirectoryLong != "" {
+		*targetDirectory = *targetDirectoryLong
+	}
+	if *noTargetDirectoryLong {
+		*noTargetDirectory = true
+	}
+	if *verboseLong {
+		*verbose = true
+	}
+	overwriteMode = getOverwriteMode()
+
+	switch {
+	case !update.Given && *updateOlder, update.Given && update.Value == "":
+		update.Value = "older"
+	case update.Value != "" && update.Value != "all" && update.Value != "none" && update.Value != "older":
+		cli.UsageError("mv", "invalid argument '%s' for '--update'\n"+
+			"Valid arguments are:\n  - 'all'\n  - 'none'\n  - 'older'", update.Value)
+	}
+
+	// -S implies a backup, as in GNU mv.
+	if *makeBackup || backupControl.Given || *suffix != "" {
+		control, err := backup.ParseControl(backupControl.Value)
+		if err != nil {
+			cli.UsageError("mv", "%s for 'backup type'\n%s", err, backup.ValidArguments)
+		}
+		backupType = control
+	}
+	if backupType != backup.NONE && *exchange {
+		cli.UsageError("mv", "options --backup and --exchange are mutually exclusive")
+	}
+	if backupType != backup.NONE && overwriteMode == OVERWRITE_NEVER {
+		cli.UsageError("mv", "options --backup and --no-clobber are mutually exclusive")
+	}
 }
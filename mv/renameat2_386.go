@@ -0,0 +1,12 @@
+//
+// renameat2_386.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Abram C. Isola, Michael Murphy
+//
+
+// +build linux
+
+package main
+
+const SYS_RENAMEAT2 = 353 // renameat2(2) system call number
@@ -0,0 +1,57 @@
+//
+// renameat2_linux.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Abram C. Isola, Michael Murphy
+//
+
+// +build linux
+
+package main
+
+import "errors"
+import "os"
+import "syscall"
+import "unsafe"
+
+// The flags of renameat2(2).
+const (
+	RENAME_NOREPLACE = 1 << 0 // Fail with EEXIST if the destination exists
+	RENAME_EXCHANGE  = 1 << 1 // Swap the source and the destination
+)
+
+const AT_FDCWD = -100 // Resolve relative names from the working directory
+
+/* renameat2 renames the file as rename(2) does, but with the flags. A kernel
+ * or file system that cannot honour them is reported as such, so that the
+ * caller does not quietly lose the guarantee they give. */
+func renameat2(oldpath, newpath string, flags int) error {
+	oldName, err := syscall.BytePtrFromString(oldpath)
+	if err != nil {
+		return err
+	}
+	newName, err := syscall.BytePtrFromString(newpath)
+	if err != nil {
+		return err
+	}
+
+	errno := syscall.ENOSYS
+	if number, fd := SYS_RENAMEAT2, AT_FDCWD; number >= 0 {
+		_, _, errno = syscall.Syscall6(uintptr(number), uintptr(fd), uintptr(unsafe.Pointer(oldName)),
+			uintptr(fd), uintptr(unsafe.Pointer(newName)), uintptr(flags), 0)
+	}
+
+	switch errno {
+	case 0:
+		return nil
+	case syscall.ENOSYS, syscall.EINVAL:
+		if flags&RENAME_EXCHANGE != 0 {
+			err = errors.New("exchanging files is not supported on this file system")
+		} else {
+			err = errors.New("renaming without replacing is not supported on this file system")
+		}
+	default:
+		err = errno
+	}
+	return &os.LinkError{Op: "renameat2", Old: oldpath, New: newpath, Err: err}
+}
@@ -0,0 +1,12 @@
+//
+// renameat2_other.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Abram C. Isola, Michael Murphy
+//
+
+// +build linux,!amd64,!386,!arm,!arm64
+
+package main
+
+const SYS_RENAMEAT2 = -1 // renameat2(2) is not wired up here
@@ -0,0 +1,41 @@
+//
+// renameat2_portable.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Abram C. Isola, Michael Murphy
+//
+
+// +build !linux
+
+package main
+
+import "errors"
+import "os"
+import "syscall"
+
+// The flags of renameat2(2).
+const (
+	RENAME_NOREPLACE = 1 << 0 // Fail with EEXIST if the destination exists
+	RENAME_EXCHANGE  = 1 << 1 // Swap the source and the destination
+)
+
+/* renameat2 renames the file as rename(2) does, but with the flags, where
+ * the system has no renameat2(2). A destination is not replaced if it is
+ * there when looked at just before the rename, which cannot rule out one
+ * made in between. Files cannot be exchanged at all. */
+func renameat2(oldpath, newpath string, flags int) error {
+	var err error
+	switch {
+	case flags&RENAME_EXCHANGE != 0:
+		err = errors.New("exchanging files is not supported on this system")
+	case flags&RENAME_NOREPLACE != 0:
+		if _, statErr := os.Lstat(newpath); statErr == nil {
+			err = syscall.EEXIST
+		} else {
+			return os.Rename(oldpath, newpath)
+		}
+	default:
+		return os.Rename(oldpath, newpath)
+	}
+	return &os.LinkError{Op: "renameat2", Old: oldpath, New: newpath, Err: err}
+}
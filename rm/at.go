@@ -0,0 +1,93 @@
+//
+// at.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Abram C. Isola
+//
+
+// +build linux
+
+package main
+
+import "os"
+import "syscall"
+import "unsafe"
+
+// The directory and flag arguments of the *at(2) system calls.
+const (
+	AT_FDCWD            = -100
+	AT_SYMLINK_NOFOLLOW = 0x100
+	AT_REMOVEDIR        = 0x200
+	O_PATH              = 0x200000
+	W_OK                = 2
+)
+
+/* fstatat gets the status of the file named in the directory open as dirfd,
+ * without following a symbolic link. Where the system call is not wired up,
+ * the file is opened as a bare location and its descriptor is stat'ed. */
+func fstatat(dirfd int, name string, stat *syscall.Stat_t) error {
+	if number := SYS_FSTATAT; number >= 0 {
+		path, err := syscall.BytePtrFromString(name)
+		if err != nil {
+			return err
+		}
+		_, _, errno := syscall.Syscall6(uintptr(number), uintptr(dirfd), uintptr(unsafe.Pointer(path)),
+			uintptr(unsafe.Pointer(stat)), AT_SYMLINK_NOFOLLOW, 0, 0)
+		if errno != 0 {
+			return errno
+		}
+		return nil
+	}
+
+	fd, err := syscall.Openat(dirfd, name, O_PATH|syscall.O_NOFOLLOW|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(fd)
+	return syscall.Fstat(fd, stat)
+}
+
+// unlinkat removes the file, or with AT_REMOVEDIR the empty directory,
+// named in the directory open as dirfd.
+func unlinkat(dirfd int, name string, flags int) error {
+	path, err := syscall.BytePtrFromString(name)
+	if err != nil {
+		return err
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_UNLINKAT, uintptr(dirfd), uintptr(unsafe.Pointer(path)), uintptr(flags))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+/* openDirectory opens the directory named in the directory open as dirfd.
+ * It fails rather than follow a symbolic link, so a directory swapped for
+ * a link while rm works cannot lead it out of the tree. */
+func openDirectory(dirfd int, name string) (*os.File, error) {
+	fd, err := syscall.Openat(dirfd, name, syscall.O_RDONLY|syscall.O_DIRECTORY|syscall.O_NOFOLLOW|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+	return os.NewFile(uintptr(fd), name), nil
+}
+
+// lstat gets the status of the entry, without following a symbolic link.
+func (e *entry) lstat(stat *syscall.Stat_t) error {
+	return fstatat(e.parent, e.name, stat)
+}
+
+// remove removes the entry, or with AT_REMOVEDIR the empty directory.
+func (e *entry) remove(flags int) error {
+	return unlinkat(e.parent, e.name, flags)
+}
+
+// openDirectory opens the directory of the entry as openDirectory does.
+func (e *entry) openDirectory() (*os.File, error) {
+	return openDirectory(e.parent, e.name)
+}
+
+// access checks whether the user may use the entry as the mode asks.
+func (e *entry) access(mode uint32) error {
+	return syscall.Faccessat(e.parent, e.name, mode, 0)
+}
@@ -0,0 +1,54 @@
+//
+// at_other.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Abram C. Isola
+//
+
+// +build !linux
+
+package main
+
+import "os"
+import "syscall"
+
+// The directory and flag arguments the *at(2) system calls would take.
+const (
+	AT_FDCWD     = -100
+	AT_REMOVEDIR = 0x200
+	W_OK         = 2
+)
+
+/* Without the *at(2) system calls, each entry is reached by its whole path,
+ * which is resolved anew each time. A directory swapped for a link while rm
+ * works is still not followed when it is opened, but a path longer than
+ * PATH_MAX cannot be removed. */
+
+// lstat gets the status of the entry, without following a symbolic link.
+func (e *entry) lstat(stat *syscall.Stat_t) error {
+	return syscall.Lstat(e.path(), stat)
+}
+
+// remove removes the entry, or with AT_REMOVEDIR the empty directory.
+func (e *entry) remove(flags int) error {
+	if flags&AT_REMOVEDIR != 0 {
+		return syscall.Rmdir(e.path())
+	}
+	return syscall.Unlink(e.path())
+}
+
+// openDirectory opens the directory of the entry, failing rather than
+// follow a symbolic link.
+func (e *entry) openDirectory() (*os.File, error) {
+	path := e.path()
+	fd, err := syscall.Open(path, syscall.O_RDONLY|syscall.O_DIRECTORY|syscall.O_NOFOLLOW|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+	return os.NewFile(uintptr(fd), path), nil
+}
+
+// access checks whether the user may use the entry as the mode asks.
+func (e *entry) access(mode uint32) error {
+	return syscall.Access(e.path(), mode)
+}
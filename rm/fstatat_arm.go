@@ -0,0 +1,12 @@
+//
+// fstatat_arm.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Abram C. Isola
+//
+
+// +build linux
+
+package main
+
+const SYS_FSTATAT = 327 // fstatat(2) system call number
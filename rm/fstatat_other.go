@@ -0,0 +1,12 @@
+//
+// fstatat_other.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Abram C. Isola
+//
+
+// +build linux,!amd64,!386,!arm,!arm64
+
+package main
+
+const SYS_FSTATAT = -1 // fstatat(2) is not wired up here, open with O_PATH and fstat
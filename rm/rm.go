@@ -4,206 +4,560 @@
 //
 // Written By: Abram C. Isola
 //
+
 package main
 
+import "bufio"
 import "flag"
 import "fmt"
-import "io"
 import "os"
+import "path/filepath"
+import "strings"
+import "sync"
+import "sync/atomic"
 import "syscall"
 
+import "github.com/aisola/go-coreutils/internal/cli"
+import "github.com/aisola/go-coreutils/internal/trash"
+
 const (
 	help_text string = `
-    Usage: rm [OPTION]...
-    
-    remove files (delete/unlink)
-
-        --help     display this help and exit
-        --version  output version information and exit
-        -f         ignore if files do not exist, never prompt
-        -i         prompt before each removal
-        -r, -R, --recursive
-            remove directories and their contents recursively
-    `
+    Usage: rm [OPTION]... [FILE]...
+
+    Remove (unlink) the FILE(s).
+
+        -help         display this help and exit
+        -version      output version information and exit
+
+        -f, -force    ignore nonexistent files and arguments, never prompt
+        -i            prompt before every removal
+        -I            prompt once before removing more than three files, or
+                      when removing recursively; less intrusive than -i,
+                      while still giving protection against most mistakes
+        -interactive[=WHEN]
+                      prompt according to WHEN: never, once (-I), or
+                      always (-i); without WHEN, prompt always
+        -one-file-system
+                      when removing a hierarchy recursively, skip any
+                      directory that is on a file system different from
+                      that of the corresponding command line argument
+        -no-preserve-root
+                      do not treat '/' specially
+        -preserve-root[=all]
+                      do not remove '/' (default); with 'all', reject any
+                      command line argument on a separate device from its
+                      parent
+        -r, -R, -recursive
+                      remove directories and their contents recursively
+        -d, -dir      remove empty directories
+        -jobs N       remove up to N directories of a tree at once
+        -trash        move the files to the trash instead of removing them
+        -v, -verbose  explain what is being done
+
+    By default, rm does not remove directories. Use the -recursive (-r or -R)
+    option to remove each listed directory, too, along with all of its
+    contents.
+
+    With -trash, files are moved to $XDG_DATA_HOME/Trash, or to the trash
+    of the file system they are on, and can be brought back with
+    trash-restore. Use trash-list to see them and trash-empty to remove them
+    for good.
+
+    To remove a file whose name starts with a '-', for example '-foo',
+    use one of these commands:
+        rm -- -foo
+        rm ./-foo
+`
 	version_text = `
     rm (go-coreutils) 0.1
 
     Copyright (C) 2014, The GO-Coreutils Developers.
     This program comes with ABSOLUTELY NO WARRANTY; for details see
-    LICENSE. This is free software, and you are welcome to redistribute 
+    LICENSE. This is free software, and you are welcome to redistribute
     it under certain conditions in LICENSE.
 `
 )
 
 var (
-	force        = flag.Bool("f", false, "ignore if files do not exist, never prompt")
-	recursiveR   = flag.Bool("R", false, "remove directories and their contents recursively")
-	recursiver   = flag.Bool("r", false, "remove directories and their contents recursively")
-	recursive    = flag.Bool("recursive", false, "remove directories and their contents recursively")
-	interactivei = flag.Bool("i", false, "prompt before each removal")
-	// interactiveI = flag.Bool("I", false, "")
+	force             = flag.Bool("f", false, "ignore nonexistent files and arguments, never prompt")
+	forceLong         = flag.Bool("force", false, "ignore nonexistent files and arguments, never prompt")
+	interactiveAlways = flag.Bool("i", false, "prompt before every removal")
+	interactiveOnce   = flag.Bool("I", false, "prompt once before removing more than three files, or when removing recursively")
+	recursiveR        = flag.Bool("R", false, "remove directories and their contents recursively")
+	recursiver        = flag.Bool("r", false, "remove directories and their contents recursively")
+	recursiveLong     = flag.Bool("recursive", false, "remove directories and their contents recursively")
+	emptyDirectories  = flag.Bool("d", false, "remove empty directories")
+	emptyDirLong      = flag.Bool("dir", false, "remove empty directories")
+	verbose           = flag.Bool("v", false, "explain what is being done")
+	verboseLong       = flag.Bool("verbose", false, "explain what is being done")
+	oneFileSystem     = flag.Bool("one-file-system", false, "skip directories on other file systems")
+	noPreserveRoot    = flag.Bool("no-preserve-root", false, "do not treat '/' specially")
+	toTrash           = flag.Bool("trash", false, "move the files to the trash instead of removing them")
+	jobs              = flag.Int("jobs", 1, "remove up to N directories of a tree at once")
+	help              = flag.Bool("help", false, "display help information")
+	version           = flag.Bool("version", false, "display version information")
+
+	interactiveWhen cli.OptionalValue
+	preserveRoot    cli.OptionalValue
 )
 
-// MODIFIED FROM THE os.RemoveAll() implimentation
-// RemoveAll removes all files/directories below
-// and prompts if the option is set.
-func RemoveAll(path string) error {
-	var answer string
-	var err error
-
-	// Is this a directory we need to recurse into?
-	dir, serr := os.Lstat(path)
-	if serr != nil {
-		if serr, ok := serr.(*os.PathError); ok && (os.IsNotExist(serr.Err) || serr.Err == syscall.ENOTDIR) {
-			return nil
-		}
-		return serr
-	}
-
-	if !dir.IsDir() {
-		// Not a directory;
-		if *interactivei {
-			fmt.Printf("rm: do you want to remove '%s'? (y/N) ", path)
-			_, err = fmt.Scanln(&answer)
-			if err != nil {
-				return err
-			}
+// When rm prompts, set by the last of -f, -i, -I and -interactive.
+const (
+	INTERACTIVE_NEVER     = iota // Never prompt
+	INTERACTIVE_SOMETIMES        // Prompt only for write-protected files
+	INTERACTIVE_ONCE             // Prompt once, then as INTERACTIVE_SOMETIMES
+	INTERACTIVE_ALWAYS           // Prompt for every file
+)
+
+var interactiveMode = INTERACTIVE_SOMETIMES
+var ignoreMissing = false
+var recursive = false
 
-			if answer == "y" || answer == "yes" {
-				os.Remove(path)
+// The flags that take their value from the following argument.
+var valueFlags = map[string]bool{"jobs": true}
+
+/* getInteractiveMode returns the prompting mode and whether missing files
+ * are ignored, as set by the last of -f, -i, -I and -interactive. */
+func getInteractiveMode() (int, bool) {
+	mode, ignore := INTERACTIVE_SOMETIMES, false
+	for _, given := range cli.GivenFlags(os.Args[1:], valueFlags) {
+		switch given.Name {
+		case "f", "force":
+			mode, ignore = INTERACTIVE_NEVER, true
+		case "i":
+			mode, ignore = INTERACTIVE_ALWAYS, false
+		case "I":
+			mode, ignore = INTERACTIVE_ONCE, false
+		case "interactive":
+			value := given.Value
+			if !given.HasValue {
+				value = "always"
+			}
+			switch value {
+			case "never", "no", "none":
+				mode = INTERACTIVE_NEVER
+			case "once":
+				mode, ignore = INTERACTIVE_ONCE, false
+			case "always", "yes":
+				mode, ignore = INTERACTIVE_ALWAYS, false
+			default:
+				cli.UsageError("rm", "invalid argument '%s' for '--interactive'\n"+
+					"Valid arguments are:\n  - 'never', 'no', 'none'\n  - 'once'\n  - 'always', 'yes'", value)
 			}
-		} else {
-			os.Remove(path)
 		}
+	}
+	return mode, ignore
+}
+
+// The answers to prompts, read a line at a time.
+var stdin = bufio.NewReader(os.Stdin)
+
+// The input function prints a prompt to the user on standard error and
+// returns true if the answer is yes.
+func input(format string, a ...interface{}) bool {
+	output.Lock()
+	defer output.Unlock()
+	fmt.Fprintf(os.Stderr, "rm: "+format, a...)
+	answer, _ := stdin.ReadString('\n')
+	return strings.HasPrefix(answer, "y") || strings.HasPrefix(answer, "Y")
+}
 
-		return nil
+// The output lock keeps the prompts, messages and -v lines of directories
+// removed at once from running into each other.
+var output sync.Mutex
+
+// The status rm exits with, set to 1 by the first failure.
+var status = 0
+
+// fail reports a failure and sets the exit status.
+func fail(format string, a ...interface{}) {
+	output.Lock()
+	defer output.Unlock()
+	fmt.Fprintf(os.Stderr, "rm: "+format+"\n", a...)
+	status = 1
+}
+
+// Returns the kind of file as GNU names it in prompts.
+func fileType(stat *syscall.Stat_t) string {
+	switch stat.Mode & syscall.S_IFMT {
+	case syscall.S_IFDIR:
+		return "directory"
+	case syscall.S_IFLNK:
+		return "symbolic link"
+	case syscall.S_IFIFO:
+		return "fifo"
+	case syscall.S_IFSOCK:
+		return "socket"
+	case syscall.S_IFCHR:
+		return "character special file"
+	case syscall.S_IFBLK:
+		return "block special file"
 	}
+	if stat.Size == 0 {
+		return "regular empty file"
+	}
+	return "regular file"
+}
 
-	// Turns out, it's a directory...
-	if !*recursiveR && !*recursiver && !*recursive {
-		fmt.Printf("rm: '%s' is a directory\n", path)
-		return nil
+// Returns true if the stat is that of a directory.
+func isDir(stat *syscall.Stat_t) bool {
+	return stat.Mode&syscall.S_IFMT == syscall.S_IFDIR
+}
+
+/* An entry is a file to remove: its name in its parent directory, which is
+ * open as parent, and the entry of that directory. The path, which can be
+ * longer than PATH_MAX, is put together only to be shown to the user. */
+type entry struct {
+	parent int
+	name   string
+	up     *entry
+}
+
+// Returns the path of the entry, as given on the command line and then
+// through each directory below it.
+func (e *entry) path() string {
+	var names []string
+	for ; e != nil; e = e.up {
+		names = append(names, e.name)
+	}
+	path := names[len(names)-1]
+	for index := len(names) - 2; index >= 0; index-- {
+		path = join(path, names[index])
+	}
+	return path
+}
+
+// Returns the name of the entry of the directory, without doubling slashes.
+func join(directory, name string) string {
+	if strings.HasSuffix(directory, "/") {
+		return directory + name
 	}
+	return directory + "/" + name
+}
+
+// Returns true if the file is not a symbolic link and the user may not
+// write to it.
+func isWriteProtected(e *entry, stat *syscall.Stat_t) bool {
+	return stat.Mode&syscall.S_IFMT != syscall.S_IFLNK && e.access(W_OK) == syscall.EACCES
+}
 
-	if *interactivei {
-		fmt.Printf("rm: descend into directory '%s'? (y/N) ", path)
-		_, err = fmt.Scanln(&answer)
-		if err != nil {
-			return err
+/* confirm asks whether to act on the file, as in "remove" or "descend
+ * into", if the prompting mode asks for it: always with -i, and otherwise
+ * only for write-protected files when standard input is a terminal. */
+func confirm(action string, e *entry, stat *syscall.Stat_t) bool {
+	if interactiveMode == INTERACTIVE_NEVER {
+		return true
+	}
+	protected := isWriteProtected(e, stat)
+	if interactiveMode != INTERACTIVE_ALWAYS && !(protected && cli.IsTerminal()) {
+		return true
+	}
+	kind := fileType(stat)
+	if protected {
+		kind = "write-protected " + kind
+	}
+	return input("%s %s '%s'? ", action, kind, e.path())
+}
+
+/* removeEntry removes the file, or the directory and, with -r, everything
+ * in it, unless the user declines. It returns false if removing something
+ * failed, which has been reported, and then the directories holding the
+ * file are left alone. The device is that of the command line argument.
+ *
+ * Each directory is opened, without following symbolic links, relative to
+ * the one holding it, and its entries are looked at and removed relative
+ * to it in turn. No path is ever resolved again, so a directory that is
+ * swapped for a link while rm works cannot lead it outside the tree, and
+ * the depth of the tree is limited only by the open file descriptors.
+ * Where the *at(2) system calls are not at hand, the paths are resolved
+ * instead, as at_other.go tells. */
+func removeEntry(e *entry, stat *syscall.Stat_t, rootDevice uint64) bool {
+	if !isDir(stat) {
+		if !confirm("remove", e, stat) {
+			return true
 		}
+		return unlink(e, 0)
+	}
 
-		if answer == "y" || answer == "yes" {
+	if !recursive {
+		if !*emptyDirectories {
+			fail("cannot remove '%s': Is a directory", e.path())
+			return false
+		}
+		if !confirm("remove", e, stat) {
+			return true
+		}
+		return unlink(e, AT_REMOVEDIR)
+	}
 
-			fd, err := os.Open(path)
-			if err != nil {
-				return err
-			}
+	if *oneFileSystem && uint64(stat.Dev) != rootDevice {
+		fail("skipping '%s', since it's on a different device", e.path())
+		return false
+	}
 
-			// Remove contents & return first error.
-			err = nil
-			for {
-				names, err1 := fd.Readdirnames(100)
-				for _, name := range names {
-					err1 := RemoveAll(path + string(os.PathSeparator) + name)
-					if err == nil {
-						err = err1
-					}
-				}
-				if err1 == io.EOF {
-					break
-				}
-				// If Readdirnames returned an error, use it.
-				if err == nil {
-					err = err1
-				}
-				if len(names) == 0 {
-					break
+	directory, err := e.openDirectory()
+	if err != nil {
+		fail("cannot remove '%s': %s", e.path(), cli.ErrorString(err))
+		return false
+	}
+
+	// The directory opened must be the one looked at, and not one swapped
+	// in since, which may be on another device.
+	var opened syscall.Stat_t
+	if err := syscall.Fstat(int(directory.Fd()), &opened); err != nil {
+		directory.Close()
+		fail("cannot remove '%s': %s", e.path(), cli.ErrorString(err))
+		return false
+	}
+	if !sameFile(&opened, stat) {
+		directory.Close()
+		fail("cannot remove '%s': %s", e.path(), cli.ErrorString(syscall.ENOENT))
+		return false
+	}
+	names, err := directory.Readdirnames(-1)
+	if err != nil {
+		directory.Close()
+		fail("cannot remove '%s': %s", e.path(), cli.ErrorString(err))
+		return false
+	}
+
+	if len(names) > 0 && !confirm("descend into", e, stat) {
+		directory.Close()
+		return true
+	}
+	var failed int32
+	var wait sync.WaitGroup
+	fd := int(directory.Fd())
+	for _, name := range names {
+		child := &entry{parent: fd, name: name, up: e}
+		var childStat syscall.Stat_t
+		if err := child.lstat(&childStat); err == syscall.ENOENT {
+			continue
+		} else if err != nil {
+			fail("cannot remove '%s': %s", child.path(), cli.ErrorString(err))
+			atomic.StoreInt32(&failed, 1)
+			continue
+		}
+		if isDir(&childStat) && startWorker() {
+			wait.Add(1)
+			go func(child *entry, childStat syscall.Stat_t) {
+				defer wait.Done()
+				defer stopWorker()
+				if !removeEntry(child, &childStat, rootDevice) {
+					atomic.StoreInt32(&failed, 1)
 				}
-			}
+			}(child, childStat)
+			continue
+		}
+		if !removeEntry(child, &childStat, rootDevice) {
+			atomic.StoreInt32(&failed, 1)
+		}
+	}
+	wait.Wait()
+	directory.Close()
 
-			// Close directory, because windows won't remove opened directory.
-			fd.Close()
+	if atomic.LoadInt32(&failed) != 0 {
+		return false
+	}
+	if !confirm("remove", e, stat) {
+		return true
+	}
+	return unlink(e, AT_REMOVEDIR)
+}
+
+/* The workers channel holds a token for each goroutine removing a directory
+ * besides the first, up to -jobs in all. A directory is handed to a new
+ * goroutine only if a token is free; otherwise the goroutine that found it
+ * removes it, so the tree is never waited on by idle goroutines. */
+var workers chan struct{}
 
-		} else {
-			return nil
+// startWorker takes a token for a new goroutine, if one is free.
+func startWorker() bool {
+	select {
+	case workers <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// stopWorker gives the token of a finished goroutine back.
+func stopWorker() {
+	<-workers
+}
+
+// unlink removes the file, or with AT_REMOVEDIR the empty directory, and
+// says so with -v.
+func unlink(e *entry, flags int) bool {
+	if err := e.remove(flags); err != nil {
+		if !(ignoreMissing && err == syscall.ENOENT) {
+			fail("cannot remove '%s': %s", e.path(), cli.ErrorString(err))
 		}
+		return false
+	}
 
-	} else {
-		fd, err := os.Open(path)
-		if err != nil {
-			return err
+	output.Lock()
+	defer output.Unlock()
+	if *verbose && flags == AT_REMOVEDIR {
+		fmt.Printf("removed directory '%s'\n", e.path())
+	} else if *verbose {
+		fmt.Printf("removed '%s'\n", e.path())
+	}
+	return true
+}
+
+// Returns true if the files are the same file.
+func sameFile(a, b *syscall.Stat_t) bool {
+	return a.Dev == b.Dev && a.Ino == b.Ino
+}
+
+/* removeOperand removes a command line argument, first refusing the ones
+ * that are too dangerous to remove recursively: '.', '..', and '/' unless
+ * -no-preserve-root is given. */
+func removeOperand(name string) {
+	var stat syscall.Stat_t
+	if err := syscall.Lstat(name, &stat); err != nil {
+		if !(ignoreMissing && err == syscall.ENOENT) {
+			fail("cannot remove '%s': %s", name, cli.ErrorString(err))
 		}
+		return
+	}
 
-		// Remove contents & return first error.
-		err = nil
-		for {
-			names, err1 := fd.Readdirnames(100)
-			for _, name := range names {
-				err1 := RemoveAll(path + string(os.PathSeparator) + name)
-				if err == nil {
-					err = err1
+	if recursive && isDir(&stat) {
+		if base := filepath.Base(name); base == "." || base == ".." {
+			fail("refusing to remove '.' or '..' directory: skipping '%s'", name)
+			return
+		}
+		var root syscall.Stat_t
+		if preserveRoot.Given || !*noPreserveRoot {
+			if err := syscall.Lstat("/", &root); err == nil && sameFile(&stat, &root) {
+				if name == "/" {
+					fail("it is dangerous to operate recursively on '/'")
+				} else {
+					fail("it is dangerous to operate recursively on '%s' (same as '/')", name)
 				}
+				fail("use --no-preserve-root to override this failsafe")
+				return
 			}
-			if err1 == io.EOF {
-				break
-			}
-			// If Readdirnames returned an error, use it.
-			if err == nil {
-				err = err1
+		}
+		var parent syscall.Stat_t
+		if preserveRoot.Value == "all" {
+			if err := syscall.Lstat(join(name, ".."), &parent); err == nil && parent.Dev != stat.Dev {
+				fail("skipping '%s', since it's on a different device", name)
+				fail("and --preserve-root=all is in effect")
+				return
 			}
-			if len(names) == 0 {
-				break
+		}
+	}
+	if *toTrash {
+		trashOperand(name, &stat)
+		return
+	}
+	removeEntry(&entry{parent: AT_FDCWD, name: name}, &stat, uint64(stat.Dev))
+}
+
+/* trashOperand moves a command line argument to the trash instead of
+ * removing it. A directory still needs -r, or -d if it is empty, as it
+ * would to be removed, and is moved whole. */
+func trashOperand(name string, stat *syscall.Stat_t) {
+	e := &entry{parent: AT_FDCWD, name: name}
+	if isDir(stat) && !recursive {
+		if !*emptyDirectories {
+			fail("cannot remove '%s': Is a directory", name)
+			return
+		}
+		if directory, err := os.Open(name); err == nil {
+			names, _ := directory.Readdirnames(1)
+			directory.Close()
+			if len(names) > 0 {
+				fail("cannot remove '%s': Directory not empty", name)
+				return
 			}
 		}
+	}
+	if !confirm("remove", e, stat) {
+		return
+	}
 
-		// Close directory, because windows won't remove opened directory.
-		fd.Close()
+	if _, err := trash.Put(name); err != nil {
+		fail("cannot move '%s' to the trash: %s", name, cli.ErrorString(err))
+		return
 	}
+	if *verbose {
+		output.Lock()
+		fmt.Printf("trashed '%s'\n", name)
+		output.Unlock()
+	}
+}
 
-	if *interactivei {
+func main() {
+	processFlags()
 
-		fmt.Printf("rm: remove '%s'? (y/N) ", path)
-		_, err := fmt.Scanln(&answer)
-		if err != nil {
-			return err
+	files := flag.Args()
+	if len(files) == 0 {
+		if ignoreMissing {
+			os.Exit(0)
 		}
+		cli.UsageError("rm", "missing operand")
+	}
 
-		if answer == "y" || answer == "yes" {
-			// Remove directory.
-			err1 := os.Remove(path)
-			if err == nil {
-				err = err1
-			}
+	if interactiveMode == INTERACTIVE_ONCE && (recursive || len(files) > 3) {
+		question := "remove %d argument%s? "
+		if recursive {
+			question = "remove %d argument%s recursively? "
 		}
-
-	} else {
-		// Remove directory.
-		err1 := os.Remove(path)
-		if err == nil {
-			err = err1
+		plural := "s"
+		if len(files) == 1 {
+			plural = ""
+		}
+		if !input(question, len(files), plural) {
+			os.Exit(0)
 		}
 	}
 
-	return err
+	for _, file := range files {
+		removeOperand(file)
+	}
+	os.Exit(status)
 }
 
-func main() {
-	help := flag.Bool("help", false, help_text)
-	version := flag.Bool("version", false, version_text)
+/* processFlags parses the command line and works out from it the prompting
+ * mode, whether to recurse, and how many directories to remove at once. */
+func processFlags() {
+	flag.Var(&interactiveWhen, "interactive", "prompt according to WHEN: never, once or always")
+	flag.Var(&preserveRoot, "preserve-root", "do not remove '/'; with 'all', reject arguments on other devices")
 	flag.Parse()
 
 	if *help {
-		fmt.Println(help_text)
+		fmt.Print(help_text)
 		os.Exit(0)
 	}
-
 	if *version {
-		fmt.Println(version_text)
+		fmt.Print(version_text)
 		os.Exit(0)
 	}
 
-	files := flag.Args()
-	for i := 0; i < len(files); i++ {
-		RemoveAll(files[i])
+	if *forceLong {
+		*force = true
+	}
+	if *emptyDirLong {
+		*emptyDirectories = true
+	}
+	if *verboseLong {
+		*verbose = true
+	}
+	recursive = *recursiveR || *recursiver || *recursiveLong
+	interactiveMode, ignoreMissing = getInteractiveMode()
+
+	// Prompting for every file keeps to the order of the tree, one at a time.
+	if *jobs < 1 {
+		cli.UsageError("rm", "invalid number of jobs: '%d'", *jobs)
+	} else if interactiveMode != INTERACTIVE_ALWAYS {
+		workers = make(chan struct{}, *jobs-1)
+	}
+
+	if preserveRoot.Value != "" && preserveRoot.Value != "all" {
+		fmt.Fprintf(os.Stderr, "rm: unrecognized --preserve-root argument: '%s'\n", preserveRoot.Value)
+		os.Exit(1)
 	}
 }
@@ -0,0 +1,152 @@
+//
+// rm_test.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Abram C. Isola
+//
+
+// +build linux
+
+package main
+
+import "io/ioutil"
+import "os"
+import "path/filepath"
+import "runtime"
+import "strconv"
+import "syscall"
+import "testing"
+
+// Returns the number of file descriptors the process has open.
+func openDescriptors(t testing.TB) int {
+	names, err := ioutil.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skip("cannot list open file descriptors:", err)
+	}
+	return len(names)
+}
+
+/* makeDeepTree makes a chain of directories the depth deep in the parent,
+ * with a file at the bottom. Each is made relative to the one above it, as
+ * the path to the bottom is far longer than PATH_MAX. */
+func makeDeepTree(t testing.TB, parent string, depth int) {
+	fd, err := syscall.Open(parent, syscall.O_RDONLY|syscall.O_DIRECTORY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for level := 0; level < depth; level++ {
+		if err := syscall.Mkdirat(fd, "d", 0700); err != nil {
+			syscall.Close(fd)
+			t.Fatalf("mkdirat at depth %d: %v", level, err)
+		}
+		next, err := syscall.Openat(fd, "d", syscall.O_RDONLY|syscall.O_DIRECTORY|syscall.O_CLOEXEC, 0)
+		syscall.Close(fd)
+		if err != nil {
+			t.Fatalf("openat at depth %d: %v", level, err)
+		}
+		fd = next
+	}
+	file, err := syscall.Openat(fd, "file", syscall.O_WRONLY|syscall.O_CREAT|syscall.O_CLOEXEC, 0600)
+	syscall.Close(fd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	syscall.Close(file)
+}
+
+// remove removes the tree with -r, as rm would the command line argument.
+func remove(t testing.TB, root string) bool {
+	var stat syscall.Stat_t
+	if err := fstatat(AT_FDCWD, root, &stat); err != nil {
+		t.Fatal(err)
+	}
+	return removeEntry(&entry{parent: AT_FDCWD, name: root}, &stat, uint64(stat.Dev))
+}
+
+func TestRemoveDeepTree(t *testing.T) {
+	const DEPTH = 10000
+
+	// A descriptor is held open for each directory on the way down.
+	var limit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &limit); err != nil || limit.Cur < DEPTH+100 {
+		t.Skipf("too few file descriptors for a tree %d deep", DEPTH)
+	}
+
+	temporary, err := ioutil.TempDir("", "rm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(temporary)
+	root := filepath.Join(temporary, "tree")
+	if err := os.Mkdir(root, 0700); err != nil {
+		t.Fatal(err)
+	}
+	makeDeepTree(t, root, DEPTH)
+
+	recursive = true
+	defer func() { recursive = false }()
+	before := openDescriptors(t)
+	if !remove(t, root) {
+		t.Fatal("removeEntry failed")
+	}
+	if after := openDescriptors(t); after != before {
+		t.Errorf("%d file descriptors open after removing, %d before", after, before)
+	}
+	if _, err := os.Lstat(root); !os.IsNotExist(err) {
+		t.Errorf("%s still exists: %v", root, err)
+	}
+	if status != 0 {
+		t.Errorf("exit status is %d", status)
+	}
+}
+
+/* makeWideTree makes a tree in the parent the depth deep, with the width of
+ * directories and as many files in each directory. */
+func makeWideTree(b *testing.B, parent string, width, depth int) {
+	for index := 0; index < width; index++ {
+		name := filepath.Join(parent, "f"+strconv.Itoa(index))
+		if err := ioutil.WriteFile(name, nil, 0600); err != nil {
+			b.Fatal(err)
+		}
+	}
+	if depth == 0 {
+		return
+	}
+	for index := 0; index < width; index++ {
+		name := filepath.Join(parent, "d"+strconv.Itoa(index))
+		if err := os.Mkdir(name, 0700); err != nil {
+			b.Fatal(err)
+		}
+		makeWideTree(b, name, width, depth-1)
+	}
+}
+
+// benchmarkRemove removes a tree of 1,555 directories and 9,330 files with
+// up to the number of jobs at once.
+func benchmarkRemove(b *testing.B, jobs int) {
+	temporary, err := ioutil.TempDir("", "rm")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(temporary)
+
+	recursive = true
+	workers = make(chan struct{}, jobs-1)
+	defer func() { recursive, workers = false, make(chan struct{}) }()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		root := filepath.Join(temporary, "tree")
+		if err := os.Mkdir(root, 0700); err != nil {
+			b.Fatal(err)
+		}
+		makeWideTree(b, root, 6, 4)
+		b.StartTimer()
+		if !remove(b, root) {
+			b.Fatal("removeEntry failed")
+		}
+	}
+}
+
+func BenchmarkRemoveJobs1(b *testing.B) { benchmarkRemove(b, 1) }
+
+func BenchmarkRemoveJobsN(b *testing.B) { benchmarkRemove(b, runtime.NumCPU()) }
@@ -8,19 +8,30 @@ package main
 
 import "flag"
 import "fmt"
+import "io"
 import "os"
+import "strings"
+import "syscall"
 
 const (
 	help_text = `
-    Usage: rmdir [OPTION] DIRECTORY...
+    Usage: rmdir [OPTION]... DIRECTORY...
 
-    Removes directories if they are empty.
+    Remove the DIRECTORY(ies), if they are empty.
+
+        -ignore-fail-on-non-empty
+              ignore each failure that is solely because a directory
+              is non-empty
+
+        -p, -parents
+              remove DIRECTORY and its ancestors; e.g., 'rmdir -p a/b/c' is
+              similar to 'rmdir a/b/c a/b a'
 
         -v, -verbose
               output a diagnostic for every directory processed
-      
+
         -help display this help and exit
-        
+
         -version output version information and exit
 `
 	version_text = `
@@ -34,10 +45,13 @@ const (
 )
 
 var (
-	verbose     = flag.Bool("v", false, "output a diagnostic for every directory processed.")
-	verboseLong = flag.Bool("verbose", false, "see verbose")
-	help        = flag.Bool("help", false, "display this help and exit")
-	version     = flag.Bool("version", false, "output version information and exit")
+	ignoreNonEmpty = flag.Bool("ignore-fail-on-non-empty", false, "ignore each failure that is solely because a directory is non-empty")
+	parents        = flag.Bool("p", false, "remove DIRECTORY and its ancestors")
+	parentsLong    = flag.Bool("parents", false, "see parents")
+	verbose        = flag.Bool("v", false, "output a diagnostic for every directory processed.")
+	verboseLong    = flag.Bool("verbose", false, "see verbose")
+	help           = flag.Bool("help", false, "display this help and exit")
+	version        = flag.Bool("version", false, "output version information and exit")
 )
 
 // printAndExit prints a message and exits the program.
@@ -46,40 +60,93 @@ func printAndExit(message string) {
 	os.Exit(0)
 }
 
-// argumentIsDir returns true if the argument is a directory.
-func argumentIsDir(dir *string) bool {
-	file, err := os.Stat(*dir)
+// errorString returns the error's underlying message, capitalised as the C
+// library's messages are.
+func errorString(err error) string {
+	if pathError, ok := err.(*os.PathError); ok {
+		err = pathError.Err
+	}
+	message := err.Error()
+	return strings.ToUpper(message[:1]) + message[1:]
+}
+
+// isEmpty returns true if the directory has no entries, and false if it has
+// some or cannot be read.
+func isEmpty(dir string) bool {
+	file, err := os.Open(dir)
 	if err != nil {
-		prefix := "rmdir: Failed to remove"
-		fmt.Printf("%s '%s': no such file or directory\n", prefix, *dir)
 		return false
-	} else if !file.IsDir() {
-		prefix := "rmdir: Failed to remove"
-		fmt.Printf("%s '%s': not a directory\n", prefix, *dir)
+	}
+	defer file.Close()
+	names, err := file.Readdirnames(1)
+	return err == io.EOF && len(names) == 0
+}
+
+/* ignorable returns true if removing the directory failed only because it
+ * is not empty and -ignore-fail-on-non-empty is given. Some systems report
+ * a non-empty directory that may not be removed anyway as EPERM, EACCES,
+ * EROFS or EBUSY rather than ENOTEMPTY, so those count if it has entries. */
+func ignorable(dir string, err error) bool {
+	if !*ignoreNonEmpty {
 		return false
-	} else {
+	}
+	switch err {
+	case syscall.ENOTEMPTY, syscall.EEXIST:
 		return true
+	case syscall.EPERM, syscall.EACCES, syscall.EROFS, syscall.EBUSY:
+		return !isEmpty(dir)
 	}
+	return false
 }
 
-// removeDirectory attempts to remove the 'dir' directory.
-func removeDirectory(dir *string) {
-	err := os.Remove(*dir)
-	if err != nil {
-		fmt.Printf("rmdir: failed to remove '%s': %s\n", *dir, err)
+/* removeDirectory removes the directory and reports how it went, returning
+ * false if it was not removed. The verbose message is only printed for a
+ * directory that has actually been removed. As in GNU, failing to remove a
+ * parent named by -p is told apart from failing to remove the operand. */
+func removeDirectory(dir string, parent bool) bool {
+	if err := syscall.Rmdir(dir); err != nil {
+		if !ignorable(dir, err) && parent {
+			fmt.Fprintf(os.Stderr, "rmdir: failed to remove directory '%s': %s\n", dir, errorString(err))
+			status = 1
+		} else if !ignorable(dir, err) {
+			fmt.Fprintf(os.Stderr, "rmdir: failed to remove '%s': %s\n", dir, errorString(err))
+			status = 1
+		}
+		return false
+	}
+	if *verbose {
+		fmt.Printf("rmdir: removing directory, '%s'\n", dir)
 	}
+	return true
 }
 
-func main() {
-	for index := 0; index < flag.NArg(); index++ {
-		arg := flag.Arg(index)
-		if *verbose || *verboseLong {
-			fmt.Printf("rmdir: removing directory, '%s'\n", arg)
+/* removeParents removes the directory and then each of its ancestors named
+ * in the path, stopping at the first that is not removed. */
+func removeParents(dir string) {
+	parent := false
+	for dir != "" && removeDirectory(dir, parent) {
+		parent = true
+		dir = strings.TrimRight(dir, "/")
+		slash := strings.LastIndex(dir, "/")
+		if slash < 0 {
+			return
 		}
-		if argumentIsDir(&arg) {
-			removeDirectory(&arg)
+		dir = strings.TrimRight(dir[:slash], "/")
+	}
+}
+
+// The status rmdir exits with, set to 1 by the first failure.
+var status = 0
+
+func main() {
+	for _, arg := range flag.Args() {
+		if *parents {
+			removeParents(arg)
+		} else {
+			removeDirectory(arg, false)
 		}
 	}
+	os.Exit(status)
 }
 
 func init() {
@@ -90,7 +157,15 @@ func init() {
 	if *version {
 		printAndExit(version_text)
 	}
+	if *parentsLong {
+		*parents = true
+	}
+	if *verboseLong {
+		*verbose = true
+	}
 	if flag.NArg() == 0 {
-		printAndExit("Try 'rmdir --help' for more information.")
+		fmt.Fprintln(os.Stderr, "rmdir: missing operand")
+		fmt.Fprintln(os.Stderr, "Try 'rmdir -help' for more information.")
+		os.Exit(1)
 	}
 }
@@ -0,0 +1,319 @@
+//
+// format.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Abram C. Isola, Michael Murphy
+//
+
+// +build linux
+
+package main
+
+import "bytes"
+import "flag"
+import "fmt"
+import "os"
+import "strconv"
+import "syscall"
+import "time"
+
+// A field is the value of a single format directive along with the fmt verb
+// that it should be printed with.
+type field struct {
+	value interface{}
+	verb  byte
+}
+
+// A directive looks up the field for a format sequence such as the 's' in
+// '%s'. The boolean is false when the sequence is not recognised.
+type directive func(verb byte) (field, bool)
+
+// expandFormat replaces every %-sequence in format with the matching field,
+// interpreting backslash escapes along the way if escapes is true.
+func expandFormat(format string, escapes bool, lookup directive) string {
+	var buffer bytes.Buffer
+	for index := 0; index < len(format); index++ {
+		switch {
+		case format[index] == '%':
+			index = writeDirective(&buffer, format, index, lookup)
+		case format[index] == '\\' && escapes:
+			index = writeEscape(&buffer, format, index)
+		default:
+			buffer.WriteByte(format[index])
+		}
+	}
+	return buffer.String()
+}
+
+// writeDirective parses the printf-style flags, width and precision of the
+// sequence starting at format[index], writes its expansion to buffer and
+// returns the index of the last byte consumed.
+func writeDirective(buffer *bytes.Buffer, format string, index int, lookup directive) int {
+	start := index
+	index++
+	for index < len(format) && bytes.IndexByte([]byte("-+ #0'"), format[index]) >= 0 {
+		index++
+	}
+	for index < len(format) && format[index] >= '0' && format[index] <= '9' {
+		index++
+	}
+	precision := -1
+	if index < len(format) && format[index] == '.' {
+		index++
+		digits := index
+		for index < len(format) && format[index] >= '0' && format[index] <= '9' {
+			index++
+		}
+		precision, _ = strconv.Atoi(format[digits:index])
+	}
+
+	if index == len(format) { // A trailing '%' is printed as is.
+		buffer.WriteString(format[start:])
+		return index
+	}
+
+	// The thousands grouping flag has no fmt equivalent, so it is dropped.
+	spec := string(bytes.Replace([]byte(format[start:index]), []byte("'"), nil, -1))
+	verb := format[index]
+	if verb == '%' {
+		buffer.WriteByte('%')
+		return index
+	}
+
+	f, ok := lookup(verb)
+	if !ok {
+		buffer.WriteByte('?')
+		return index
+	}
+	buffer.WriteString(formatField(spec, precision, f))
+	return index
+}
+
+// formatField prints a field using the flags and width in spec. Timestamps
+// are printed as seconds, with precision digits of the fractional part.
+func formatField(spec string, precision int, f field) string {
+	switch value := f.value.(type) {
+	case time.Time:
+		seconds := strconv.FormatInt(value.Unix(), 10)
+		if precision > 0 {
+			if precision > 9 {
+				precision = 9
+			}
+			fraction := fmt.Sprintf("%09d", value.Nanosecond())
+			seconds += "." + fraction[:precision]
+		}
+		if dot := bytes.IndexByte([]byte(spec), '.'); dot >= 0 {
+			spec = spec[:dot]
+		}
+		return fmt.Sprintf(spec+"s", seconds)
+	case string:
+		return fmt.Sprintf(spec+"s", value)
+	}
+	return fmt.Sprintf(spec+string(f.verb), f.value)
+}
+
+// writeEscape writes the character for the backslash escape starting at
+// format[index] and returns the index of the last byte consumed.
+func writeEscape(buffer *bytes.Buffer, format string, index int) int {
+	if index+1 == len(format) {
+		fmt.Fprintln(os.Stderr, "stat: warning: backslash at end of format")
+		buffer.WriteByte('\\')
+		return index
+	}
+	index++
+
+	switch c := format[index]; c {
+	case 'a':
+		buffer.WriteByte('\a')
+	case 'b':
+		buffer.WriteByte('\b')
+	case 'e':
+		buffer.WriteByte('\x1b')
+	case 'f':
+		buffer.WriteByte('\f')
+	case 'n':
+		buffer.WriteByte('\n')
+	case 'r':
+		buffer.WriteByte('\r')
+	case 't':
+		buffer.WriteByte('\t')
+	case 'v':
+		buffer.WriteByte('\v')
+	case '"', '\\':
+		buffer.WriteByte(c)
+	case '0', '1', '2', '3', '4', '5', '6', '7':
+		value := 0
+		for end := index + 3; index < end && index < len(format) && format[index] >= '0' && format[index] <= '7'; index++ {
+			value = value*8 + int(format[index]-'0')
+		}
+		buffer.WriteByte(byte(value))
+		index--
+	case 'x':
+		value, digits := 0, 0
+		for ; digits < 2 && index+1 < len(format); digits++ {
+			digit, err := strconv.ParseUint(format[index+1:index+2], 16, 8)
+			if err != nil {
+				break
+			}
+			value = value*16 + int(digit)
+			index++
+		}
+		if digits == 0 {
+			fmt.Fprintln(os.Stderr, "stat: missing hex digit for \\x")
+			buffer.WriteByte('x')
+		} else {
+			buffer.WriteByte(byte(value))
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "stat: warning: unrecognized escape '\\%c'\n", c)
+		buffer.WriteByte(c)
+	}
+	return index
+}
+
+// Returns the major number of a device.
+func deviceMajor(dev uint64) uint64 {
+	return (dev>>8)&0xfff | (dev>>32)&^0xfff
+}
+
+// Returns the minor number of a device.
+func deviceMinor(dev uint64) uint64 {
+	return dev&0xff | (dev>>12)&^0xff
+}
+
+// Returns the access rights in the human readable form used by ls -l.
+func modeString(mode uint32) string {
+	buffer := []byte("?rwxrwxrwx")
+	switch mode & syscall.S_IFMT {
+	case syscall.S_IFREG:
+		buffer[0] = '-'
+	case syscall.S_IFDIR:
+		buffer[0] = 'd'
+	case syscall.S_IFLNK:
+		buffer[0] = 'l'
+	case syscall.S_IFCHR:
+		buffer[0] = 'c'
+	case syscall.S_IFBLK:
+		buffer[0] = 'b'
+	case syscall.S_IFIFO:
+		buffer[0] = 'p'
+	case syscall.S_IFSOCK:
+		buffer[0] = 's'
+	}
+	for bit := uint(0); bit < 9; bit++ {
+		if mode&(1<<(8-bit)) == 0 {
+			buffer[bit+1] = '-'
+		}
+	}
+
+	// The special bits replace the execute bit of their class.
+	special := func(position int, set bool, lower, upper byte) {
+		if !set {
+			return
+		}
+		if buffer[position] == 'x' {
+			buffer[position] = lower
+		} else {
+			buffer[position] = upper
+		}
+	}
+	special(3, mode&syscall.S_ISUID != 0, 's', 'S')
+	special(6, mode&syscall.S_ISGID != 0, 's', 'S')
+	special(9, mode&syscall.S_ISVTX != 0, 't', 'T')
+	return string(buffer)
+}
+
+// Returns the SELinux security context of a file, or '?' if it has none.
+func securityContext(path string) string {
+	buffer := make([]byte, 256)
+	size, err := syscall.Getxattr(path, "security.selinux", buffer)
+	if err != nil || size == 0 {
+		return "?"
+	}
+	return string(bytes.TrimRight(buffer[:size], "\x00"))
+}
+
+// fileDirective returns the lookup for the file format sequences of the
+// operand at index.
+func fileDirective(file *fileStat, index int) directive {
+	name := flag.Arg(index)
+	return func(verb byte) (field, bool) {
+		switch verb {
+		case 'a':
+			return field{file.mode & 07777, 'o'}, true
+		case 'A':
+			return field{modeString(file.mode), 's'}, true
+		case 'b':
+			return field{file.blocks, 'd'}, true
+		case 'B':
+			return field{512, 'd'}, true
+		case 'C':
+			return field{securityContext(name), 's'}, true
+		case 'd':
+			return field{file.dev, 'd'}, true
+		case 'D':
+			return field{file.dev, 'x'}, true
+		case 'f':
+			return field{file.mode, 'x'}, true
+		case 'F':
+			return field{getType(file), 's'}, true
+		case 'g':
+			return field{file.gid, 'd'}, true
+		case 'G':
+			return field{lookupGroupID(fmt.Sprintf("%d", file.gid)), 's'}, true
+		case 'h':
+			return field{file.nlink, 'd'}, true
+		case 'i':
+			return field{file.ino, 'd'}, true
+		case 'k':
+			return field{getAttributes(file), 's'}, true
+		case 'M':
+			if !file.hasMountID {
+				return field{"?", 's'}, true
+			}
+			return field{file.mountID, 'd'}, true
+		case 'n':
+			return field{name, 's'}, true
+		case 'N':
+			if file.mode&syscall.S_IFMT == syscall.S_IFLNK {
+				return field{fmt.Sprintf("'%s' -> '%s'", name, readLink(index)), 's'}, true
+			}
+			return field{fmt.Sprintf("'%s'", name), 's'}, true
+		case 'o':
+			return field{file.blksize, 'd'}, true
+		case 's':
+			return field{file.size, 'd'}, true
+		case 't':
+			return field{deviceMajor(file.rdev), 'x'}, true
+		case 'T':
+			return field{deviceMinor(file.rdev), 'x'}, true
+		case 'u':
+			return field{file.uid, 'd'}, true
+		case 'U':
+			return field{lookupUserID(fmt.Sprintf("%d", file.uid)), 's'}, true
+		case 'w':
+			if !file.hasBirth {
+				return field{"-", 's'}, true
+			}
+			return field{formatTime(file.btime), 's'}, true
+		case 'W':
+			if !file.hasBirth {
+				return field{0, 'd'}, true
+			}
+			return field{file.btime, 'd'}, true
+		case 'x':
+			return field{formatTime(file.atime), 's'}, true
+		case 'X':
+			return field{file.atime, 'd'}, true
+		case 'y':
+			return field{formatTime(file.mtime), 's'}, true
+		case 'Y':
+			return field{file.mtime, 'd'}, true
+		case 'z':
+			return field{formatTime(file.ctime), 's'}, true
+		case 'Z':
+			return field{file.ctime, 'd'}, true
+		}
+		return field{}, false
+	}
+}
@@ -0,0 +1,144 @@
+//
+// json.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Abram C. Isola, Michael Murphy
+//
+
+// +build linux
+
+package main
+
+import "encoding/json"
+import "flag"
+import "fmt"
+import "os"
+import "syscall"
+
+// The layout of timestamps in JSON output: RFC 3339 with all nine digits of
+// the nanoseconds, so that the values line up and sort as strings.
+const jsonTimeLayout = "2006-01-02T15:04:05.000000000Z07:00"
+
+// jsonEntry is the structured form of a file's status. Operands that could
+// not be stat'ed carry only their name and the error.
+type jsonEntry struct {
+	Name  string `json:"name"`
+	Error string `json:"error,omitempty"`
+	*jsonStatus
+}
+
+// jsonStatus holds the fields of a jsonEntry that come from statx or lstat.
+type jsonStatus struct {
+	Type           string   `json:"type,omitempty"`
+	LinkTarget     string   `json:"link_target,omitempty"`
+	Mode           string   `json:"mode,omitempty"`
+	Permissions    string   `json:"permissions,omitempty"`
+	RawMode        uint32   `json:"raw_mode,omitempty"`
+	Size           int64    `json:"size"`
+	Blocks         int64    `json:"blocks"`
+	BlockSize      int64    `json:"block_size"`
+	IOBlock        int64    `json:"io_block"`
+	Device         uint64   `json:"device"`
+	DeviceMajor    uint64   `json:"device_major"`
+	DeviceMinor    uint64   `json:"device_minor"`
+	Inode          uint64   `json:"inode"`
+	Links          uint64   `json:"links"`
+	Uid            uint32   `json:"uid"`
+	User           string   `json:"user,omitempty"`
+	Gid            uint32   `json:"gid"`
+	Group          string   `json:"group,omitempty"`
+	Rdev           uint64   `json:"rdev"`
+	RdevMajor      uint64   `json:"rdev_major"`
+	RdevMinor      uint64   `json:"rdev_minor"`
+	Access         string   `json:"atime,omitempty"`
+	Modify         string   `json:"mtime,omitempty"`
+	Change         string   `json:"ctime,omitempty"`
+	Birth          string   `json:"btime,omitempty"`
+	MountID        *uint64  `json:"mount_id,omitempty"`
+	Attributes     []string `json:"attributes,omitempty"`
+	AttributesMask uint64   `json:"attributes_mask"`
+}
+
+// Returns the name of a user or group, or an empty string if the ID does not
+// resolve to one.
+func resolvedName(lookup func(string) string, id uint32) string {
+	name := lookup(fmt.Sprintf("%d", id))
+	if name == fmt.Sprintf("%d", id) {
+		return ""
+	}
+	return name
+}
+
+// Builds the structured form of the status of the operand at index.
+func getJSONEntry(index int) jsonEntry {
+	entry := jsonEntry{Name: flag.Arg(index)}
+	file, err := getFileStat(index)
+	if err != nil {
+		entry.Error = errorString(err)
+		return entry
+	}
+
+	entry.jsonStatus = &jsonStatus{}
+	entry.Type = getType(file)
+	if file.mode&syscall.S_IFMT == syscall.S_IFLNK {
+		if target, err := os.Readlink(entry.Name); err == nil {
+			entry.LinkTarget = target
+		}
+	}
+	entry.Mode = fmt.Sprintf("%04o", file.mode&07777)
+	entry.Permissions = modeString(file.mode)
+	entry.RawMode = file.mode
+	entry.Size = file.size
+	entry.Blocks = file.blocks
+	entry.BlockSize = 512
+	entry.IOBlock = file.blksize
+	entry.Device = file.dev
+	entry.DeviceMajor = deviceMajor(file.dev)
+	entry.DeviceMinor = deviceMinor(file.dev)
+	entry.Inode = file.ino
+	entry.Links = file.nlink
+	entry.Uid = file.uid
+	entry.User = resolvedName(lookupUserID, file.uid)
+	entry.Gid = file.gid
+	entry.Group = resolvedName(lookupGroupID, file.gid)
+	entry.Rdev = file.rdev
+	entry.RdevMajor = deviceMajor(file.rdev)
+	entry.RdevMinor = deviceMinor(file.rdev)
+	entry.Access = file.atime.Format(jsonTimeLayout)
+	entry.Modify = file.mtime.Format(jsonTimeLayout)
+	entry.Change = file.ctime.Format(jsonTimeLayout)
+	if file.hasBirth {
+		entry.Birth = file.btime.Format(jsonTimeLayout)
+	}
+	if file.hasMountID {
+		entry.MountID = &file.mountID
+	}
+	entry.Attributes = getAttributeList(file)
+	entry.AttributesMask = file.attributesMask
+	return entry
+}
+
+// Prints the status of every argument as JSON, either as a single array or
+// as one object per line, returning the exit status.
+func jsonLoop(lines bool) int {
+	status := 0
+	entries := make([]jsonEntry, 0, flag.NArg())
+	encoder := json.NewEncoder(os.Stdout)
+	for index := 0; index < flag.NArg(); index++ {
+		entry := getJSONEntry(index)
+		if entry.Error != "" {
+			status = 1
+		}
+		if lines {
+			encoder.Encode(entry)
+		} else {
+			entries = append(entries, entry)
+		}
+	}
+
+	if !lines {
+		output, _ := json.MarshalIndent(entries, "", "  ")
+		fmt.Println(string(output))
+	}
+	return status
+}
@@ -19,25 +19,94 @@ import "syscall"
 import "time"
 
 const (
-	isExecutable = 0111              // isExcutable
-	isSymlink    = os.ModeSymlink    // isSymlink
-	isDevice     = os.ModeDevice     // isDevice
-	isCharDevice = os.ModeCharDevice // isCharDevice
+	// The default layouts used for files and for device files.
+	fileFormat = "  File: %N\n  Size: %-10s\tBlocks: %-10b IO Block: %-6o %F\n" +
+		"Device: %Dh/%dd\tInode: %-10i  Links: %h\n" + ownerFormat + timeFormat
+	deviceFormat = "  File: %N\n  Size: %-10s\tBlocks: %-10b IO Block: %-6o %F\n" +
+		"Device: %Dh/%dd\tInode: %-10i  Links: %-5h Device type: %t,%T\n" + ownerFormat + timeFormat
+	ownerFormat = "Access: (%04a/%10.10A)  Uid: (%5u/%8U)   Gid: (%5g/%8G)\n"
+	timeFormat  = "Access: %x\nModify: %y\nChange: %z\n Birth: %w\n"
+
+	// The layout of human readable timestamps.
+	timeLayout = "2006-01-02 15:04:05.000000000 -0700"
 
 	help_text string = `
-    Usage: stat [FILE]...
-       or: stat [OPTION]
+    Usage: stat [OPTION]... FILE...
     
     display file or file system status
-    
-    THIS IS PROGRAM IS IN PROGRESS
 
         -L, -dereference
               follow links
+
+        -f, -file-system
+              display file system status instead of file status
+
+        -c, -format=FORMAT
+              use the specified FORMAT instead of the default;
+              output a newline after each use of FORMAT
+
+        -printf=FORMAT
+              like --format, but interpret backslash escapes,
+              and do not output a mandatory trailing newline
           
+        -json
+              print the status of all files as a JSON array
+
+        -json-lines
+              print the status of each file as a JSON object on its own line
+
         --help     display this help and exit
 
         --version  output version information and exit
+
+    The valid format sequences for files:
+
+        %a   access rights in octal
+        %A   access rights in human readable form
+        %b   number of blocks allocated (see %B)
+        %B   the size in bytes of each block reported by %b
+        %C   SELinux security context string
+        %d   device number in decimal
+        %D   device number in hex
+        %f   raw mode in hex
+        %F   file type
+        %g   group ID of owner
+        %G   group name of owner
+        %h   number of hard links
+        %i   inode number
+        %k   file attributes reported by statx, such as immutable
+        %M   mount ID
+        %n   file name
+        %N   quoted file name with dereference if symbolic link
+        %o   optimal I/O transfer size hint
+        %s   total size, in bytes
+        %t   major device type in hex, for character/block device files
+        %T   minor device type in hex, for character/block device files
+        %u   user ID of owner
+        %U   user name of owner
+        %w   time of file birth, human-readable; - if unknown
+        %W   time of file birth, seconds since Epoch; 0 if unknown
+        %x   time of last access, human-readable
+        %X   time of last access, seconds since Epoch
+        %y   time of last data modification, human-readable
+        %Y   time of last data modification, seconds since Epoch
+        %z   time of last status change, human-readable
+        %Z   time of last status change, seconds since Epoch
+
+    Valid format sequences for file systems:
+
+        %a   free blocks available to non-superuser
+        %b   total data blocks in file system
+        %c   total file nodes in file system
+        %d   free file nodes in file system
+        %f   free blocks in file system
+        %i   file system ID in hex
+        %l   maximum length of filenames
+        %n   file name
+        %s   block size (for faster transfers)
+        %S   fundamental block size (for block counts)
+        %t   file system type in hex
+        %T   file system type in human readable form
     `
 	version_text = `
     stat (go-coreutils) 0.1
@@ -52,6 +121,13 @@ const (
 var (
 	dereference     = flag.Bool("L", false, "")
 	dereferenceLong = flag.Bool("dereference", false, "")
+	fileSystem      = flag.Bool("f", false, "")
+	fileSystemLong  = flag.Bool("file-system", false, "")
+	format          = flag.String("c", "", "")
+	formatLong      = flag.String("format", "", "")
+	printfFormat    = flag.String("printf", "", "")
+	jsonOutput      = flag.Bool("json", false, "")
+	jsonLines       = flag.Bool("json-lines", false, "")
 )
 
 // Process the initial flags.
@@ -59,21 +135,29 @@ func processFlags() {
 	if *dereferenceLong {
 		*dereference = true
 	}
+	if *fileSystemLong {
+		*fileSystem = true
+	}
+	if *formatLong != "" {
+		*format = *formatLong
+	}
 }
 
-// Obtain file statistics
-func getFileStat(index int) os.FileInfo {
-	fi, err := os.Lstat(flag.Arg(0))
-	if err != nil {
-		fmt.Printf("stat: fatal: could not open '%s': %s\n", flag.Arg(0), err)
-		os.Exit(0)
-	}
-	return fi
+// Obtain file statistics, following symbolic links in dereference mode.
+func getFileStat(index int) (*fileStat, error) {
+	return statFile(flag.Arg(index), *dereference)
 }
 
-// Obtains all file statistics information
-func getAdditionalFileStat(fi os.FileInfo) *syscall.Stat_t {
-	return fi.Sys().(*syscall.Stat_t)
+// Returns the underlying error message of a path error, as GNU prints it.
+func errorString(err error) string {
+	if perr, ok := err.(*os.PathError); ok {
+		err = perr.Err
+	}
+	message := err.Error()
+	if message == "" {
+		return message
+	}
+	return strings.ToUpper(message[:1]) + message[1:]
 }
 
 // Opens the passwd file and returns a buffer of it's contents.
@@ -144,20 +228,27 @@ func lookupGroupID(gid string) string {
 }
 
 // Obtain the file mode type
-func getType(file os.FileInfo) string {
-	switch {
-	case file.IsDir():
+func getType(file *fileStat) string {
+	switch file.mode & syscall.S_IFMT {
+	case syscall.S_IFREG:
+		if file.size == 0 {
+			return "regular empty file"
+		}
+		return "regular file"
+	case syscall.S_IFDIR:
 		return "directory"
-	case file.Mode()&isSymlink != 0:
+	case syscall.S_IFLNK:
 		return "symbolic link"
-	case file.Mode()&isDevice != 0:
-		return "device file"
-	case file.Mode()&isCharDevice != 0:
+	case syscall.S_IFIFO:
+		return "fifo"
+	case syscall.S_IFSOCK:
+		return "socket"
+	case syscall.S_IFBLK:
+		return "block special file"
+	case syscall.S_IFCHR:
 		return "character special file"
-	case file.Mode()&isExecutable != 0:
-		return "executable file"
 	}
-	return "regular file"
+	return "weird file"
 }
 
 // Convert timespec to time
@@ -165,6 +256,11 @@ func timespecToTime(ts syscall.Timespec) time.Time {
 	return time.Unix(int64(ts.Sec), int64(ts.Nsec))
 }
 
+// Format a time the way GNU stat does, with nanoseconds and a numeric zone.
+func formatTime(t time.Time) string {
+	return t.Format(timeLayout)
+}
+
 // Resolve the symbolic link
 func readLink(index int) string {
 	sympath, err := os.Readlink(flag.Arg(index))
@@ -175,50 +271,60 @@ func readLink(index int) string {
 	}
 }
 
-// If the file is a symbolic link, check if dereference mode is enabled.
-// If dereference mode is enabled, only the path of the symbolic link is printed.
-// If it is not enabled, the symlink and it's path will be printed side by side.
-func dereferenceCheck(file os.FileInfo, index int) {
-	if *dereference {
-		fmt.Printf("  File: '%s'\n", readLink(index))
-	} else {
-		fmt.Printf("  File: '%s' -> '%s'\n", file.Name(), readLink(index))
-	}
-}
-
-// Checks whether the file is a symbolic link and prints the file name line.
-func printFileName(file os.FileInfo, index int) {
-	if getType(file) == "symbolic link" {
-		dereferenceCheck(file, index)
-	} else {
-		fmt.Printf("  File: '%s'\n", file.Name())
+// The default printing mode
+func defaultMode(file *fileStat, index int) {
+	layout := fileFormat
+	if file.mode&syscall.S_IFMT == syscall.S_IFCHR || file.mode&syscall.S_IFMT == syscall.S_IFBLK {
+		layout = deviceFormat
 	}
+	fmt.Print(expandFormat(layout, false, fileDirective(file, index)))
 }
 
-// The default printing mode
-func defaultMode(fi os.FileInfo, sys *syscall.Stat_t, userName, groupName string, index int) {
-	printFileName(fi, index)
-	fmt.Printf("  Size: %-12d Blocks: %-8d IO Block: %d %s\n", fi.Size(), sys.Blocks, sys.Blksize, getType(fi))
-
-	// device, inode, links, permissions, uid, gid
-	fmt.Printf("Device: %-12s Inode : %-8d Links: %d\n", fmt.Sprintf("%Xh/%dd", sys.Dev, sys.Dev), sys.Ino, sys.Nlink)
-	fmt.Printf("Access: %s Uid: %s Gid: %s\n", fmt.Sprintf("(%#o/%s)", fi.Mode().Perm(), fi.Mode()), fmt.Sprintf("( %d/ %s)", sys.Uid, userName), fmt.Sprintf("( %d/ %s)", sys.Gid, groupName))
+// Prints the status of the file system containing each argument given,
+// returning the exit status.
+func fileSystemLoop() int {
+	status := 0
+	for index := 0; index < flag.NArg(); index++ {
+		var fs syscall.Statfs_t
+		if err := syscall.Statfs(flag.Arg(index), &fs); err != nil {
+			fmt.Fprintf(os.Stderr, "stat: cannot read file system information for '%s': %s\n", flag.Arg(index), errorString(err))
+			status = 1
+			continue
+		}
 
-	// print out times
-	fmt.Printf("Access: %s\n", timespecToTime(sys.Atim))
-	fmt.Printf("Modify: %s\n", timespecToTime(sys.Mtim))
-	fmt.Printf("Change: %s\n", timespecToTime(sys.Ctim))
+		switch {
+		case *printfFormat != "":
+			fmt.Print(expandFormat(*printfFormat, true, fileSystemDirective(&fs, flag.Arg(index))))
+		case *format != "":
+			fmt.Println(expandFormat(*format, false, fileSystemDirective(&fs, flag.Arg(index))))
+		default:
+			fmt.Println(expandFormat(fileSystemFormat, false, fileSystemDirective(&fs, flag.Arg(index))))
+		}
+	}
+	return status
 }
 
-// Loops through each argument given.
-func argumentLoop() {
+// Loops through each argument given, returning the exit status.
+func argumentLoop() int {
+	status := 0
 	for index := 0; index < flag.NArg(); index++ {
-		fi := getFileStat(index)                         // Get file stats
-		sys := getAdditionalFileStat(fi)                 // Get lower level file statistics.
-		usr := lookupUserID(fmt.Sprintf("%d", sys.Uid))  // Get user name
-		grp := lookupGroupID(fmt.Sprintf("%d", sys.Gid)) // Get group name
-		defaultMode(fi, sys, usr, grp, index)            // Send file information for printing.
+		file, err := getFileStat(index) // Get file stats
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "stat: cannot stat '%s': %s\n", flag.Arg(index), errorString(err))
+			status = 1
+			continue
+		}
+
+		switch {
+		case *printfFormat != "":
+			fmt.Print(expandFormat(*printfFormat, true, fileDirective(file, index)))
+		case *format != "":
+			fmt.Println(expandFormat(*format, false, fileDirective(file, index)))
+		default:
+			defaultMode(file, index) // Send file information for printing.
+		}
 	}
+	return status
 }
 
 func main() {
@@ -228,14 +334,29 @@ func main() {
 	processFlags()
 
 	if *help {
-		fmt.Println(help_text)
+		os.Stdout.WriteString(help_text + "\n") // The help text contains % sequences.
 		os.Exit(0)
 	}
 
 	if *version {
-		fmt.Println(version_text)
+		fmt.Print(version_text)
 		os.Exit(0)
 	}
 
-	argumentLoop()
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "stat: missing operand\nTry 'stat --help' for more information.")
+		os.Exit(1)
+	}
+
+	if *jsonOutput || *jsonLines {
+		if *fileSystem || *format != "" || *printfFormat != "" {
+			fmt.Fprintln(os.Stderr, "stat: JSON output cannot be combined with --file-system, --format or --printf")
+			os.Exit(1)
+		}
+		os.Exit(jsonLoop(*jsonLines))
+	}
+	if *fileSystem {
+		os.Exit(fileSystemLoop())
+	}
+	os.Exit(argumentLoop())
 }
@@ -0,0 +1,204 @@
+//
+// statx.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Abram C. Isola, Michael Murphy
+//
+
+// +build linux
+
+package main
+
+import "strings"
+import "syscall"
+import "time"
+import "unsafe"
+
+const (
+	AT_FDCWD            = -100   // Resolve relative paths from the working directory
+	AT_SYMLINK_NOFOLLOW = 0x100  // Do not follow a trailing symbolic link
+	STATX_BASIC_STATS   = 0x7ff  // Everything that stat(2) reports
+	STATX_BTIME         = 0x800  // Request the birth time
+	STATX_MNT_ID        = 0x1000 // Request the mount ID
+)
+
+// The file attributes reported by statx(2), in the order they are printed.
+var fileAttributes = []struct {
+	bit  uint64
+	name string
+}{
+	{0x4, "compressed"},
+	{0x10, "immutable"},
+	{0x20, "append-only"},
+	{0x40, "nodump"},
+	{0x800, "encrypted"},
+	{0x1000, "automount"},
+	{0x2000, "mount-root"},
+	{0x100000, "verity"},
+	{0x200000, "dax"},
+}
+
+// statxTimestamp mirrors struct statx_timestamp.
+type statxTimestamp struct {
+	Sec      int64
+	Nsec     uint32
+	Reserved int32
+}
+
+// statxBuffer mirrors struct statx from linux/stat.h.
+type statxBuffer struct {
+	Mask           uint32
+	Blksize        uint32
+	Attributes     uint64
+	Nlink          uint32
+	Uid            uint32
+	Gid            uint32
+	Mode           uint16
+	Spare0         uint16
+	Ino            uint64
+	Size           uint64
+	Blocks         uint64
+	AttributesMask uint64
+	Atime          statxTimestamp
+	Btime          statxTimestamp
+	Ctime          statxTimestamp
+	Mtime          statxTimestamp
+	RdevMajor      uint32
+	RdevMinor      uint32
+	DevMajor       uint32
+	DevMinor       uint32
+	MntID          uint64
+	DioMemAlign    uint32
+	DioOffsetAlign uint32
+	Spare3         [12]uint64
+}
+
+// fileStat holds the status of a file, as reported by statx(2) or lstat(2).
+type fileStat struct {
+	dev, ino, nlink, rdev      uint64
+	mode, uid, gid             uint32
+	size, blksize, blocks      int64
+	atime, mtime, ctime, btime time.Time
+	hasBirth                   bool
+	mountID                    uint64
+	hasMountID                 bool
+	attributes                 uint64
+	attributesMask             uint64
+}
+
+// Set once statx(2) turns out to be unavailable, so it is not retried.
+var statxUnsupported = SYS_STATX < 0
+
+// Combines a major and minor number into a device number, like makedev(3).
+func makeDevice(major, minor uint32) uint64 {
+	return uint64(minor&0xff) | uint64(major&0xfff)<<8 |
+		uint64(minor&^0xff)<<12 | uint64(major&^0xfff)<<32
+}
+
+// Convert statx timestamp to time
+func statxTimestampToTime(ts statxTimestamp) time.Time {
+	return time.Unix(ts.Sec, int64(ts.Nsec))
+}
+
+// statFile obtains the status of the file at path, using statx(2) when the
+// kernel supports it and falling back to stat(2) or lstat(2) otherwise.
+func statFile(path string, follow bool) (*fileStat, error) {
+	if !statxUnsupported {
+		file, err := statxFile(path, follow)
+		if err != syscall.ENOSYS && err != syscall.EPERM {
+			return file, err
+		}
+		statxUnsupported = true
+	}
+
+	var sys syscall.Stat_t
+	var err error
+	if follow {
+		err = syscall.Stat(path, &sys)
+	} else {
+		err = syscall.Lstat(path, &sys)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &fileStat{
+		dev:     uint64(sys.Dev),
+		ino:     uint64(sys.Ino),
+		nlink:   uint64(sys.Nlink),
+		rdev:    uint64(sys.Rdev),
+		mode:    uint32(sys.Mode),
+		uid:     sys.Uid,
+		gid:     sys.Gid,
+		size:    int64(sys.Size),
+		blksize: int64(sys.Blksize),
+		blocks:  int64(sys.Blocks),
+		atime:   timespecToTime(sys.Atim),
+		mtime:   timespecToTime(sys.Mtim),
+		ctime:   timespecToTime(sys.Ctim),
+	}, nil
+}
+
+// statxFile obtains the status of the file at path with statx(2).
+func statxFile(path string, follow bool) (*fileStat, error) {
+	name, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	flags := AT_SYMLINK_NOFOLLOW
+	if follow {
+		flags = 0
+	}
+
+	var stx statxBuffer
+	number, fd := SYS_STATX, AT_FDCWD
+	if number < 0 {
+		return nil, syscall.ENOSYS
+	}
+	_, _, errno := syscall.Syscall6(uintptr(number), uintptr(fd), uintptr(unsafe.Pointer(name)),
+		uintptr(flags), STATX_BASIC_STATS|STATX_BTIME|STATX_MNT_ID, uintptr(unsafe.Pointer(&stx)), 0)
+	if errno != 0 {
+		return nil, errno
+	}
+
+	return &fileStat{
+		dev:            makeDevice(stx.DevMajor, stx.DevMinor),
+		ino:            stx.Ino,
+		nlink:          uint64(stx.Nlink),
+		rdev:           makeDevice(stx.RdevMajor, stx.RdevMinor),
+		mode:           uint32(stx.Mode),
+		uid:            stx.Uid,
+		gid:            stx.Gid,
+		size:           int64(stx.Size),
+		blksize:        int64(stx.Blksize),
+		blocks:         int64(stx.Blocks),
+		atime:          statxTimestampToTime(stx.Atime),
+		mtime:          statxTimestampToTime(stx.Mtime),
+		ctime:          statxTimestampToTime(stx.Ctime),
+		btime:          statxTimestampToTime(stx.Btime),
+		hasBirth:       stx.Mask&STATX_BTIME != 0,
+		mountID:        stx.MntID,
+		hasMountID:     stx.Mask&STATX_MNT_ID != 0,
+		attributes:     stx.Attributes,
+		attributesMask: stx.AttributesMask,
+	}, nil
+}
+
+// Returns the names of the attributes set on a file.
+func getAttributeList(file *fileStat) []string {
+	names := make([]string, 0)
+	for _, attribute := range fileAttributes {
+		if file.attributes&attribute.bit != 0 {
+			names = append(names, attribute.name)
+		}
+	}
+	return names
+}
+
+// Returns the attributes set on a file as a list, or "-" if none are.
+func getAttributes(file *fileStat) string {
+	names := getAttributeList(file)
+	if len(names) == 0 {
+		return "-"
+	}
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,12 @@
+//
+// statx_arm64.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Abram C. Isola, Michael Murphy
+//
+
+// +build linux
+
+package main
+
+const SYS_STATX = 291 // statx(2) system call number
@@ -0,0 +1,12 @@
+//
+// statx_other.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Abram C. Isola, Michael Murphy
+//
+
+// +build linux,!amd64,!386,!arm,!arm64
+
+package main
+
+const SYS_STATX = -1 // statx(2) is not wired up here, always use lstat(2)
@@ -4,68 +4,302 @@
 //
 // Written By: Abram C. Isola
 //
+
 package main
 
+import "errors"
 import "flag"
 import "fmt"
 import "os"
+import "strconv"
+import "strings"
+import "syscall"
 import "time"
 
+import "github.com/aisola/go-coreutils/internal/parsedate"
+
 const (
 	help_text string = `
-    Usage: touch [OPTION]...
-    
-    set/modify file timestamps
+    Usage: touch [OPTION]... FILE...
+
+    Update the access and modification times of each FILE to the current
+    time. A FILE argument that does not exist is created empty, unless -c
+    or -h is supplied. A FILE argument of - changes the times of the file
+    associated with standard output.
 
-        --help      display this help and exit
-        --version   output version information and exit
+        -help       display this help and exit
+        -version    output version information and exit
 
-        -c          do not create if file does not exist
-        -t=time     set time to time
-    `
+        -a          change only the access time
+        -c, -no-create
+                    do not create any files
+        -d, -date=STRING
+                    parse STRING and use it instead of current time
+        -h, -no-dereference
+                    affect each symbolic link instead of any referenced file
+        -m          change only the modification time
+        -r, -reference=FILE
+                    use this file's times instead of current time
+        -t STAMP    use [[CC]YY]MMDDhhmm[.ss] instead of current time
+        -time=WORD  change the specified time: WORD is access, atime, or use:
+                    equivalent to -a; WORD is modify or mtime: equivalent to -m
+`
 	version_text = `
     touch (go-coreutils) 0.1
 
     Copyright (C) 2014, The GO-Coreutils Developers.
     This program comes with ABSOLUTELY NO WARRANTY; for details see
-    LICENSE. This is free software, and you are welcome to redistribute 
+    LICENSE. This is free software, and you are welcome to redistribute
     it under certain conditions in LICENSE.
 `
 )
 
-func main() {
-	create := flag.Bool("c", false, "do not create if file does not exist")
-	// newTime := flag.Int("t", 0, "set to time provided")
-	help := flag.Bool("help", false, help_text)
-	version := flag.Bool("version", false, version_text)
-	flag.Parse()
+// The special nanosecond values of utimensat(2), which stand for the
+// current time and for a time not to be changed.
+const (
+	UTIME_NOW  = (1 << 30) - 1
+	UTIME_OMIT = (1 << 30) - 2
+)
 
-	if *help {
-		fmt.Println(help_text)
-		os.Exit(0)
-	}
+var (
+	accessOnly        = flag.Bool("a", false, "change only the access time")
+	modifyOnly        = flag.Bool("m", false, "change only the modification time")
+	noCreate          = flag.Bool("c", false, "do not create any files")
+	noCreateLong      = flag.Bool("no-create", false, "do not create any files")
+	dateString        = flag.String("d", "", "parse STRING and use it instead of current time")
+	dateStringLong    = flag.String("date", "", "parse STRING and use it instead of current time")
+	noDereference     = flag.Bool("h", false, "affect each symbolic link instead of any referenced file")
+	noDereferenceLong = flag.Bool("no-dereference", false, "affect each symbolic link instead of any referenced file")
+	reference         = flag.String("r", "", "use this file's times instead of current time")
+	referenceLong     = flag.String("reference", "", "use this file's times instead of current time")
+	stamp             = flag.String("t", "", "use [[CC]YY]MMDDhhmm[.ss] instead of current time")
+	timeWord          = flag.String("time", "", "change the specified time: access, atime, use, modify or mtime")
+	help              = flag.Bool("help", false, "display help information")
+	version           = flag.Bool("version", false, "display version information")
+)
 
-	if *version {
-		fmt.Println(version_text)
-		os.Exit(0)
+// Returns the timespec of t.
+func toTimespec(t time.Time) syscall.Timespec {
+	return syscall.NsecToTimespec(t.UnixNano())
+}
+
+// Returns the time of a timespec.
+func fromTimespec(ts syscall.Timespec) time.Time {
+	return time.Unix(int64(ts.Sec), int64(ts.Nsec))
+}
+
+/* parseStamp parses the -t STAMP, [[CC]YY]MMDDhhmm[.ss], in the local time
+ * zone. A two-digit year YY below 69 is in the 2000s, and otherwise in the
+ * 1900s; without a year, the current year is used. */
+func parseStamp(stamp string) (time.Time, error) {
+	invalid := fmt.Errorf("invalid date format '%s'", stamp)
+	digits, seconds := stamp, "00"
+	if dot := len(stamp) - 3; dot >= 0 && stamp[dot] == '.' {
+		digits, seconds = stamp[:dot], stamp[dot+1:]
+	}
+	for _, c := range digits + seconds {
+		if c < '0' || c > '9' {
+			return time.Time{}, invalid
+		}
 	}
 
-	files := flag.Args()
+	location := parsedate.LocalZone()
+	year := time.Now().In(location).Year()
+	switch len(digits) {
+	case 8:
+	case 10:
+		year, _ = strconv.Atoi(digits[:2])
+		if year < 69 {
+			year += 2000
+		} else {
+			year += 1900
+		}
+		digits = digits[2:]
+	case 12:
+		year, _ = strconv.Atoi(digits[:4])
+		digits = digits[4:]
+	default:
+		return time.Time{}, invalid
+	}
 
-	for i := 0; i < len(files); i++ {
-		now := time.Now()
+	field := func(index int) int {
+		value, _ := strconv.Atoi(digits[index : index+2])
+		return value
+	}
+	month, day, hour, minute := field(0), field(2), field(4), field(6)
+	second, _ := strconv.Atoi(seconds)
+	if second == 60 {
+		second = 59 // A leap second, which time zones cannot show
+	}
+	t := time.Date(year, time.Month(month), day, hour, minute, second, 0, location)
+	if int(t.Month()) != month || t.Day() != day || t.Hour() != hour || t.Minute() != minute || t.Second() != second {
+		return time.Time{}, invalid
+	}
+	return t, nil
+}
 
-		err := os.Chtimes(files[i], now, now)
-		if err != nil && *create {
-			fmt.Printf("touch: cannot touch '%s'\n", files[i])
-			os.Exit(1)
+/* getTimes returns the access and modification times to set, with UTIME_OMIT
+ * for a time that is not to be changed. Without a time source both are
+ * UTIME_NOW, so the kernel uses its own clock and permits the change for
+ * any user who can write to the file. */
+func getTimes() ([2]syscall.Timespec, error) {
+	var times [2]syscall.Timespec
+	sources := 0
+	for _, source := range []string{*dateString, *reference, *stamp} {
+		if source != "" {
+			sources++
 		}
+	}
+	// A date may be relative to the times of the reference file.
+	if sources > 1 && !(sources == 2 && *dateString != "" && *reference != "") {
+		return times, errors.New("cannot specify times from more than one source")
+	}
 
-		f, err := os.OpenFile(files[i], os.O_CREATE, 0644)
+	times[0].Nsec, times[1].Nsec = UTIME_NOW, UTIME_NOW
+	switch {
+	case *reference != "":
+		var stat syscall.Stat_t
+		statFunction := syscall.Stat
+		if *noDereference {
+			statFunction = syscall.Lstat
+		}
+		if err := statFunction(*reference, &stat); err != nil {
+			return times, fmt.Errorf("failed to get attributes of '%s': %s", *reference, errorString(err))
+		}
+		times = statTimes(&stat)
+		if *dateString != "" {
+			for index := range times {
+				now := fromTimespec(times[index]).In(parsedate.LocalZone())
+				t, err := parsedate.Parse(*dateString, now)
+				if err != nil {
+					return times, fmt.Errorf("invalid date format '%s'", *dateString)
+				}
+				times[index] = toTimespec(t)
+			}
+		}
+	case *dateString != "":
+		t, err := parsedate.Parse(*dateString, time.Now().In(parsedate.LocalZone()))
 		if err != nil {
-			fmt.Printf("touch: cannot touch `%s': %s\n", files[i], err)
-			os.Exit(1)
+			return times, fmt.Errorf("invalid date format '%s'", *dateString)
+		}
+		times[0], times[1] = toTimespec(t), toTimespec(t)
+	case *stamp != "":
+		t, err := parseStamp(*stamp)
+		if err != nil {
+			return times, err
+		}
+		times[0], times[1] = toTimespec(t), toTimespec(t)
+	}
+
+	if *accessOnly && !*modifyOnly {
+		times[1].Nsec = UTIME_OMIT
+	}
+	if *modifyOnly && !*accessOnly {
+		times[0].Nsec = UTIME_OMIT
+	}
+	return times, nil
+}
+
+// errorString returns the message of the error, capitalised as the C
+// library's messages are.
+func errorString(err error) string {
+	if pathError, ok := err.(*os.PathError); ok {
+		err = pathError.Err
+	}
+	message := err.Error()
+	if message == "" {
+		return message
+	}
+	return strings.ToUpper(message[:1]) + message[1:]
+}
+
+/* touch creates the file if it does not exist, unless -c or -h is given,
+ * and sets its times. A missing file is skipped silently with -c. If the
+ * file could not be created or opened, that error is only reported when
+ * setting the times fails as well, since a file that cannot be written may
+ * still be touched by its owner. */
+func touch(name string, times [2]syscall.Timespec) error {
+	if name == "-" {
+		if err := setTimes(os.Stdout, "", &times, false); err != nil {
+			return fmt.Errorf("setting times of '%s': %s", name, errorString(err))
+		}
+		return nil
+	}
+
+	var openError error
+	if !*noCreate && !*noDereference {
+		file, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|syscall.O_NOCTTY|syscall.O_NONBLOCK, 0666)
+		if err == nil {
+			file.Close()
+		}
+		openError = err
+	}
+
+	err := setTimes(nil, name, &times, *noDereference)
+	switch {
+	case err == nil:
+		return nil
+	case err == syscall.ENOENT && *noCreate:
+		return nil
+	case openError != nil && !os.IsExist(openError):
+		return fmt.Errorf("cannot touch '%s': %s", name, errorString(openError))
+	}
+	return fmt.Errorf("setting times of '%s': %s", name, errorString(err))
+}
+
+func main() {
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "touch: missing file operand")
+		fmt.Fprintln(os.Stderr, "Try 'touch -help' for more information.")
+		os.Exit(1)
+	}
+
+	times, err := getTimes()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "touch: %s\n", err)
+		os.Exit(1)
+	}
+
+	status := 0
+	for _, name := range flag.Args() {
+		if err := touch(name, times); err != nil {
+			fmt.Fprintf(os.Stderr, "touch: %s\n", err)
+			status = 1
 		}
-		f.Close()
+	}
+	os.Exit(status)
+}
+
+func init() {
+	flag.Parse()
+	if *help {
+		fmt.Print(help_text)
+		os.Exit(0)
+	}
+	if *version {
+		fmt.Print(version_text)
+		os.Exit(0)
+	}
+	if *noCreateLong {
+		*noCreate = true
+	}
+	if *dateStringLong != "" {
+		*dateString = *dateStringLong
+	}
+	if *noDereferenceLong {
+		*noDereference = true
+	}
+	if *referenceLong != "" {
+		*reference = *referenceLong
+	}
+	switch *timeWord {
+	case "":
+	case "access", "atime", "use":
+		*accessOnly = true
+	case "modify", "mtime":
+		*modifyOnly = true
+	default:
+		fmt.Fprintf(os.Stderr, "touch: invalid argument '%s' for '-time'\n", *timeWord)
+		os.Exit(1)
 	}
 }
@@ -0,0 +1,52 @@
+//
+// utimensat_linux.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Abram C. Isola
+//
+
+// +build linux
+
+package main
+
+import "os"
+import "syscall"
+import "unsafe"
+
+// The directory and flag arguments of utimensat(2).
+const (
+	AT_FDCWD            = -100
+	AT_SYMLINK_NOFOLLOW = 0x100
+)
+
+// utimensat sets the times of the file relative to the directory dirfd, or
+// of dirfd itself if the name is empty.
+func utimensat(dirfd int, name string, times *[2]syscall.Timespec, flags int) error {
+	var path *byte
+	if name != "" {
+		var err error
+		if path, err = syscall.BytePtrFromString(name); err != nil {
+			return err
+		}
+	}
+	_, _, errno := syscall.Syscall6(syscall.SYS_UTIMENSAT, uintptr(dirfd), uintptr(unsafe.Pointer(path)),
+		uintptr(unsafe.Pointer(times)), uintptr(flags), 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+/* setTimes sets the times of the open file, or else of the file with the
+ * name, or of the link itself if noDereference. The kernel takes UTIME_NOW
+ * and UTIME_OMIT as they are. */
+func setTimes(file *os.File, name string, times *[2]syscall.Timespec, noDereference bool) error {
+	if file != nil {
+		return utimensat(int(file.Fd()), "", times, 0)
+	}
+	flags := 0
+	if noDereference {
+		flags = AT_SYMLINK_NOFOLLOW
+	}
+	return utimensat(AT_FDCWD, name, times, flags)
+}
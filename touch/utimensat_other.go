@@ -0,0 +1,62 @@
+//
+// utimensat_other.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Abram C. Isola
+//
+
+// +build !linux
+
+package main
+
+import "os"
+import "syscall"
+import "time"
+
+/* setTimes sets the times of the open file, or else of the file with the
+ * name, with os.Chtimes where utimensat(2) is not at hand. UTIME_NOW is the
+ * current time and UTIME_OMIT the time the file has. The times of a
+ * symbolic link itself cannot be set this way. */
+func setTimes(file *os.File, name string, times *[2]syscall.Timespec, noDereference bool) error {
+	var stat syscall.Stat_t
+	if file != nil {
+		name = file.Name()
+		if err := syscall.Fstat(int(file.Fd()), &stat); err != nil {
+			return err
+		}
+	} else {
+		if err := syscall.Lstat(name, &stat); err != nil {
+			return err
+		}
+		if stat.Mode&syscall.S_IFMT == syscall.S_IFLNK {
+			if noDereference {
+				return syscall.ENOSYS
+			}
+			if err := syscall.Stat(name, &stat); err != nil {
+				return err
+			}
+		}
+	}
+
+	current := statTimes(&stat)
+	var resolved [2]time.Time
+	for index, ts := range times {
+		switch ts.Nsec {
+		case UTIME_NOW:
+			resolved[index] = time.Now()
+		case UTIME_OMIT:
+			resolved[index] = fromTimespec(current[index])
+		default:
+			resolved[index] = fromTimespec(ts)
+		}
+	}
+	return underlying(os.Chtimes(name, resolved[0], resolved[1]))
+}
+
+// Returns the system call error beneath the error of the os package.
+func underlying(err error) error {
+	if pathError, ok := err.(*os.PathError); ok {
+		return pathError.Err
+	}
+	return err
+}
@@ -0,0 +1,99 @@
+//
+// trash-empty.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Abram C. Isola
+//
+
+package main
+
+import "flag"
+import "fmt"
+import "os"
+import "strconv"
+import "time"
+
+import "github.com/aisola/go-coreutils/internal/trash"
+
+const (
+	help_text string = `
+    Usage: trash-empty [OPTION]... [DAYS]
+
+    Remove the files in the trash for good. With DAYS, remove only the files
+    that were trashed more than DAYS days ago.
+
+        -help         display this help and exit
+        -version      output version information and exit
+
+        -v, -verbose  explain what is being done
+`
+	version_text = `
+    trash-empty (go-coreutils) 0.1
+
+    Copyright (C) 2014, The GO-Coreutils Developers.
+    This program comes with ABSOLUTELY NO WARRANTY; for details see
+    LICENSE. This is free software, and you are welcome to redistribute
+    it under certain conditions in LICENSE.
+`
+)
+
+var (
+	verbose     = flag.Bool("v", false, "explain what is being done")
+	verboseLong = flag.Bool("verbose", false, "explain what is being done")
+	help        = flag.Bool("help", false, "display help information")
+	version     = flag.Bool("version", false, "display version information")
+)
+
+func main() {
+	files := flag.Args()
+	cutoff := time.Now()
+	switch {
+	case len(files) > 1:
+		fmt.Fprintf(os.Stderr, "trash-empty: extra operand '%s'\n", files[1])
+		fmt.Fprintln(os.Stderr, "Try 'trash-empty -help' for more information.")
+		os.Exit(1)
+	case len(files) == 1:
+		days, err := strconv.Atoi(files[0])
+		if err != nil || days < 0 {
+			fmt.Fprintf(os.Stderr, "trash-empty: invalid number of days: '%s'\n", files[0])
+			os.Exit(1)
+		}
+		cutoff = cutoff.AddDate(0, 0, -days)
+	}
+
+	items, err := trash.List()
+	status := 0
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "trash-empty: %s\n", err)
+		status = 1
+	}
+	for _, item := range items {
+		if len(files) == 1 && !item.Deleted.Before(cutoff) {
+			continue
+		}
+		if err := item.Remove(); err != nil {
+			fmt.Fprintf(os.Stderr, "trash-empty: cannot remove '%s': %s\n", item.Path, err)
+			status = 1
+		} else if *verbose {
+			fmt.Printf("removed '%s'\n", item.Path)
+		}
+	}
+	os.Exit(status)
+}
+
+func init() {
+	flag.Parse()
+
+	if *help {
+		fmt.Print(help_text)
+		os.Exit(0)
+	}
+	if *version {
+		fmt.Print(version_text)
+		os.Exit(0)
+	}
+
+	if *verboseLong {
+		*verbose = true
+	}
+}
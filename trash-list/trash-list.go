@@ -0,0 +1,63 @@
+//
+// trash-list.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Abram C. Isola
+//
+
+package main
+
+import "flag"
+import "fmt"
+import "os"
+
+import "github.com/aisola/go-coreutils/internal/trash"
+
+const (
+	help_text string = `
+    Usage: trash-list [OPTION]...
+
+    List the files in the trash, oldest first, with the time each was
+    trashed and the path it was trashed from.
+
+        -help         display this help and exit
+        -version      output version information and exit
+`
+	version_text = `
+    trash-list (go-coreutils) 0.1
+
+    Copyright (C) 2014, The GO-Coreutils Developers.
+    This program comes with ABSOLUTELY NO WARRANTY; for details see
+    LICENSE. This is free software, and you are welcome to redistribute
+    it under certain conditions in LICENSE.
+`
+)
+
+var (
+	help    = flag.Bool("help", false, "display help information")
+	version = flag.Bool("version", false, "display version information")
+)
+
+func main() {
+	items, err := trash.List()
+	for _, item := range items {
+		fmt.Printf("%s %s\n", item.Deleted.Format("2006-01-02 15:04:05"), item.Path)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "trash-list: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func init() {
+	flag.Parse()
+
+	if *help {
+		fmt.Print(help_text)
+		os.Exit(0)
+	}
+	if *version {
+		fmt.Print(version_text)
+		os.Exit(0)
+	}
+}
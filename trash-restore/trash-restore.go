@@ -0,0 +1,129 @@
+//
+// trash-restore.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Abram C. Isola
+//
+
+package main
+
+import "flag"
+import "fmt"
+import "os"
+import "path/filepath"
+import "strings"
+
+import "github.com/aisola/go-coreutils/internal/trash"
+
+const (
+	help_text string = `
+    Usage: trash-restore [OPTION]... FILE...
+
+    Put each FILE back where it was trashed from. If FILE was trashed more
+    than once, the latest one is restored. A file that has since been made
+    at the same path is not replaced.
+
+        -help         display this help and exit
+        -version      output version information and exit
+
+        -v, -verbose  explain what is being done
+`
+	version_text = `
+    trash-restore (go-coreutils) 0.1
+
+    Copyright (C) 2014, The GO-Coreutils Developers.
+    This program comes with ABSOLUTELY NO WARRANTY; for details see
+    LICENSE. This is free software, and you are welcome to redistribute
+    it under certain conditions in LICENSE.
+`
+)
+
+var (
+	verbose     = flag.Bool("v", false, "explain what is being done")
+	verboseLong = flag.Bool("verbose", false, "explain what is being done")
+	help        = flag.Bool("help", false, "display help information")
+	version     = flag.Bool("version", false, "display version information")
+)
+
+// Returns the error's underlying message, capitalised as the C library's
+// messages are.
+func errorString(err error) string {
+	switch e := err.(type) {
+	case *os.PathError:
+		err = e.Err
+	case *os.LinkError:
+		err = e.Err
+	}
+	message := err.Error()
+	if message == "" {
+		return message
+	}
+	return strings.ToUpper(message[:1]) + message[1:]
+}
+
+/* restore puts the latest trashed file from the path back, returning the
+ * exit status. The items are oldest first, so the last match is taken. */
+func restore(items []trash.Item, name string) int {
+	path, err := filepath.Abs(name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "trash-restore: cannot restore '%s': %s\n", name, errorString(err))
+		return 1
+	}
+
+	for index := len(items) - 1; index >= 0; index-- {
+		if items[index].Path != path {
+			continue
+		}
+		if err := items[index].Restore(); err != nil {
+			fmt.Fprintf(os.Stderr, "trash-restore: cannot restore '%s': %s\n", name, errorString(err))
+			return 1
+		}
+		if *verbose {
+			fmt.Printf("restored '%s'\n", path)
+		}
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "trash-restore: cannot restore '%s': Not in the trash\n", name)
+	return 1
+}
+
+func main() {
+	files := flag.Args()
+	if len(files) == 0 {
+		fmt.Fprintln(os.Stderr, "trash-restore: missing operand")
+		fmt.Fprintln(os.Stderr, "Try 'trash-restore -help' for more information.")
+		os.Exit(1)
+	}
+
+	items, err := trash.List()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "trash-restore: %s\n", err)
+		os.Exit(1)
+	}
+	status := 0
+	for _, file := range files {
+		if restore(items, file) != 0 {
+			status = 1
+		}
+		// A restored item is gone from the trash, so look again.
+		items, _ = trash.List()
+	}
+	os.Exit(status)
+}
+
+func init() {
+	flag.Parse()
+
+	if *help {
+		fmt.Print(help_text)
+		os.Exit(0)
+	}
+	if *version {
+		fmt.Print(version_text)
+		os.Exit(0)
+	}
+
+	if *verboseLong {
+		*verbose = true
+	}
+}
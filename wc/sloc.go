@@ -0,0 +1,291 @@
+//
+// sloc.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Michael Murphy
+//
+package main
+
+import "path/filepath"
+import "strings"
+
+// The kinds of line that -sloc tells apart.
+const (
+	LINE_BLANK   = iota // Only white space
+	LINE_COMMENT        // Only comments and white space
+	LINE_CODE           // Anything else
+)
+
+// A comment is the syntax of a block comment, which may hold others of its
+// kind in languages that let them nest.
+type comment struct {
+	open, close string
+	nested      bool
+}
+
+// A quote is the syntax of a string literal: whether a backslash escapes
+// the character after it, and whether the string may run over lines.
+type quote struct {
+	open, close string
+	escape      bool
+	multiline   bool
+}
+
+/* A language is the comment and string syntax of a programming language,
+ * and how its files are recognised: by extension, by name, or by the
+ * interpreter named on the #! line. A line comment that must follow white
+ * space, as # in the shell, is marked as such. */
+type language struct {
+	name         string
+	extensions   []string
+	names        []string
+	interpreters []string
+	line         []string
+	block        []comment
+	quotes       []quote
+	afterSpace   bool
+}
+
+// The string syntaxes that most languages share.
+var (
+	doubleQuote = quote{`"`, `"`, true, false}
+	singleQuote = quote{`'`, `'`, true, false}
+	cComment    = comment{"/*", "*/", false}
+)
+
+// The languages -sloc knows. Longer openings of a kind come first, so that
+// """ is not taken for an empty string.
+var languages = []*language{
+	{name: "C", extensions: []string{".c", ".h"},
+		line: []string{"//"}, block: []comment{cComment}, quotes: []quote{doubleQuote, singleQuote}},
+	{name: "C++", extensions: []string{".cc", ".cpp", ".cxx", ".hh", ".hpp", ".hxx"},
+		line: []string{"//"}, block: []comment{cComment}, quotes: []quote{doubleQuote, singleQuote}},
+	{name: "C#", extensions: []string{".cs"},
+		line: []string{"//"}, block: []comment{cComment}, quotes: []quote{doubleQuote, singleQuote}},
+	{name: "CSS", extensions: []string{".css"},
+		block: []comment{cComment}, quotes: []quote{doubleQuote, singleQuote}},
+	{name: "Go", extensions: []string{".go"},
+		line: []string{"//"}, block: []comment{cComment},
+		quotes: []quote{{"`", "`", false, true}, doubleQuote, singleQuote}},
+	{name: "Haskell", extensions: []string{".hs"},
+		line: []string{"--"}, block: []comment{{"{-", "-}", true}}, quotes: []quote{doubleQuote}},
+	{name: "HTML", extensions: []string{".html", ".htm", ".xml", ".svg"},
+		block: []comment{{"<!--", "-->", false}}},
+	{name: "Java", extensions: []string{".java"},
+		line: []string{"//"}, block: []comment{cComment},
+		quotes: []quote{{`"""`, `"""`, true, true}, doubleQuote, singleQuote}},
+	{name: "JavaScript", extensions: []string{".js", ".mjs", ".cjs", ".jsx"}, interpreters: []string{"node"},
+		line: []string{"//"}, block: []comment{cComment},
+		quotes: []quote{{"`", "`", true, true}, doubleQuote, singleQuote}},
+	{name: "Kotlin", extensions: []string{".kt", ".kts"},
+		line: []string{"//"}, block: []comment{{"/*", "*/", true}},
+		quotes: []quote{{`"""`, `"""`, false, true}, doubleQuote, singleQuote}},
+	{name: "Lua", extensions: []string{".lua"}, interpreters: []string{"lua"},
+		line: []string{"--"}, block: []comment{{"--[[", "]]", false}},
+		quotes: []quote{{"[[", "]]", false, true}, doubleQuote, singleQuote}},
+	{name: "Make", names: []string{"Makefile", "makefile", "GNUmakefile"}, extensions: []string{".mk"},
+		line: []string{"#"}},
+	{name: "Perl", extensions: []string{".pl", ".pm"}, interpreters: []string{"perl"},
+		line: []string{"#"}, quotes: []quote{{`"`, `"`, true, true}, {`'`, `'`, true, true}}, afterSpace: true},
+	{name: "PHP", extensions: []string{".php"}, interpreters: []string{"php"},
+		line: []string{"//", "#"}, block: []comment{cComment}, quotes: []quote{{`"`, `"`, true, true}, {`'`, `'`, true, true}}},
+	{name: "Python", extensions: []string{".py", ".pyw"}, interpreters: []string{"python"},
+		line:   []string{"#"},
+		quotes: []quote{{`"""`, `"""`, true, true}, {`'''`, `'''`, true, true}, doubleQuote, singleQuote}},
+	{name: "Ruby", extensions: []string{".rb"}, names: []string{"Rakefile", "Gemfile"}, interpreters: []string{"ruby"},
+		line: []string{"#"}, block: []comment{{"=begin", "=end", false}}, quotes: []quote{doubleQuote, singleQuote}},
+	{name: "Rust", extensions: []string{".rs"},
+		line: []string{"//"}, block: []comment{{"/*", "*/", true}}, quotes: []quote{{`"`, `"`, true, true}}},
+	{name: "Shell", extensions: []string{".sh", ".bash", ".zsh", ".ksh"},
+		interpreters: []string{"sh", "bash", "zsh", "dash", "ksh"},
+		line:         []string{"#"}, quotes: []quote{{`"`, `"`, true, true}, {`'`, `'`, false, true}}, afterSpace: true},
+	{name: "SQL", extensions: []string{".sql"},
+		line: []string{"--"}, block: []comment{cComment}, quotes: []quote{{`'`, `'`, false, true}}},
+	{name: "Swift", extensions: []string{".swift"},
+		line: []string{"//"}, block: []comment{{"/*", "*/", true}},
+		quotes: []quote{{`"""`, `"""`, true, true}, doubleQuote}},
+	{name: "TOML", extensions: []string{".toml"},
+		line: []string{"#"}, quotes: []quote{{`"""`, `"""`, true, true}, doubleQuote, {`'`, `'`, false, false}}},
+	{name: "TypeScript", extensions: []string{".ts", ".tsx"},
+		line: []string{"//"}, block: []comment{cComment},
+		quotes: []quote{{"`", "`", true, true}, doubleQuote, singleQuote}},
+	{name: "YAML", extensions: []string{".yaml", ".yml"},
+		line: []string{"#"}, quotes: []quote{doubleQuote}, afterSpace: true},
+	{name: "Dockerfile", names: []string{"Dockerfile", "Containerfile"},
+		line: []string{"#"}},
+}
+
+// The language of files that are none of the others, whose lines are code
+// unless they are blank.
+var plainText = &language{name: "Text"}
+
+// Returns the language of the file with the name, by its name or its
+// extension, or nil if neither tells.
+func languageOf(name string) *language {
+	base := filepath.Base(name)
+	extension := strings.ToLower(filepath.Ext(base))
+	for _, l := range languages {
+		for _, known := range l.names {
+			if base == known {
+				return l
+			}
+		}
+		for _, known := range l.extensions {
+			if extension == known {
+				return l
+			}
+		}
+	}
+	return nil
+}
+
+/* Returns the language whose interpreter the #! line names, directly or
+ * through env, as in #!/usr/bin/env python3, or nil if there is none. A
+ * version after the name, as in python3.11, is ignored. */
+func languageOfShebang(line string) *language {
+	fields := strings.Fields(strings.TrimPrefix(line, "#!"))
+	if len(fields) == 0 {
+		return nil
+	}
+	interpreter := filepath.Base(fields[0])
+	if interpreter == "env" {
+		interpreter = ""
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "-") && !strings.Contains(field, "=") {
+				interpreter = filepath.Base(field)
+				break
+			}
+		}
+	}
+	interpreter = strings.TrimRight(interpreter, "0123456789.")
+	for _, l := range languages {
+		for _, known := range l.interpreters {
+			if interpreter == known {
+				return l
+			}
+		}
+	}
+	return nil
+}
+
+/* A slocState follows a file line by line, carrying the comment or string
+ * that a line leaves open to the next. The language is found from the #!
+ * line if the file's name did not tell it. */
+type slocState struct {
+	language *language
+	lines    int
+	block    *comment
+	depth    int
+	quote    *quote
+}
+
+// Returns the state for the file with the name, which is empty or - for
+// standard input.
+func newSlocState(name string) *slocState {
+	return &slocState{language: languageOf(name)}
+}
+
+// Returns true if the marker starts line comments in the language.
+func (l *language) hasLineComment(marker string) bool {
+	for _, known := range l.line {
+		if known == marker {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns true if the character is white space within a line.
+func isBlank(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v'
+}
+
+/* classify returns the kind of the next line of the file. A line is code if
+ * anything but white space lies outside its comments, and the inside of a
+ * string is code, so that comment syntax within strings is not taken for a
+ * comment. */
+func (s *slocState) classify(text string) int {
+	s.lines++
+	if s.lines == 1 && strings.HasPrefix(text, "#!") && (s.language == nil || s.language.hasLineComment("#")) {
+		if s.language == nil {
+			s.language = languageOfShebang(text)
+		}
+		return LINE_COMMENT
+	}
+	if s.language == nil {
+		s.language = plainText
+	}
+	l := s.language
+
+	code, commented := false, false
+scan:
+	for index := 0; index < len(text); {
+		rest := text[index:]
+		switch {
+		case s.block != nil:
+			commented = commented || !isBlank(rest[0])
+			if s.block.nested && strings.HasPrefix(rest, s.block.open) {
+				s.depth++
+				index += len(s.block.open)
+			} else if strings.HasPrefix(rest, s.block.close) {
+				index += len(s.block.close)
+				if s.depth--; s.depth == 0 {
+					s.block = nil
+				}
+			} else {
+				index++
+			}
+			continue
+		case s.quote != nil:
+			code = true
+			if s.quote.escape && rest[0] == '\\' {
+				index += 2
+			} else if strings.HasPrefix(rest, s.quote.close) {
+				index += len(s.quote.close)
+				s.quote = nil
+			} else {
+				index++
+			}
+			continue
+		case isBlank(rest[0]):
+			index++
+			continue
+		}
+
+		for i := range l.block {
+			if strings.HasPrefix(rest, l.block[i].open) {
+				s.block, s.depth = &l.block[i], 1
+				commented = true
+				index += len(l.block[i].open)
+				continue scan
+			}
+		}
+		for i := range l.quotes {
+			if strings.HasPrefix(rest, l.quotes[i].open) {
+				s.quote = &l.quotes[i]
+				code = true
+				index += len(l.quotes[i].open)
+				continue scan
+			}
+		}
+		for _, marker := range l.line {
+			if strings.HasPrefix(rest, marker) && (!l.afterSpace || index == 0 || isBlank(text[index-1])) {
+				commented = true
+				break scan
+			}
+		}
+		code = true
+		index++
+	}
+
+	if s.quote != nil && !s.quote.multiline {
+		s.quote = nil // An unterminated string ends with its line.
+	}
+	switch {
+	case code:
+		return LINE_CODE
+	case commented:
+		return LINE_COMMENT
+	}
+	return LINE_BLANK
+}
@@ -6,12 +6,17 @@
 //
 package main
 
-import "bufio"
 import "bytes"
 import "flag"
 import "fmt"
+import "io"
+import "io/ioutil"
 import "os"
+import "sort"
 import "strings"
+import "sync"
+import "sync/atomic"
+import "unicode"
 import "unicode/utf8"
 
 var (
@@ -22,53 +27,76 @@ var (
 	countLines              = flag.Bool("l", false, "Print the newline counts")
 	countLinesL             = flag.Bool("lines", false, "Print the newline counts")
 	countSLOC               = flag.Bool("sloc", false, "Print the source lines of code")
+	slocByLanguage          = flag.Bool("sloc-by-language", false, "Print the blank, comment and code lines of each language")
 	occurrenceRef           = flag.String("o", "", "Print the occurrences of a particular word or phrase")
 	countWords              = flag.Bool("w", false, "Print the word counts")
 	countWordsL             = flag.Bool("words", false, "Print the word counts")
 	maxLineLength           = flag.Bool("L", false, "Print the length of the longest line")
 	maxLineLengthL          = flag.Bool("max-line-length", false, "Print the length of the longest line")
+	files0From              = flag.String("files0-from", "", "Read the input files from the NUL-terminated names in F")
+	totalWhen               = flag.String("total", "auto", "When to print a line with total counts")
+	jobs                    = flag.Int("j", 1, "Count up to N files at once")
 	help_text        string = `
     Usage: wc [OPTION]... [FILE]...
-       
+       or: wc [OPTION]... -files0-from=F
+
     Print newline, word, and byte counts for each FILE, and a total line if
-    more than one FILE is specifileed. With no FILE, or when FILE is -,
+    more than one FILE is specified. With no FILE, or when FILE is -,
     read standard input. A word is a non-zero-length sequence of characters
     delimited by white spaces.
-    
-    
+
+
     The options below may be used to select which counts are printed, always in
     the following order: newline, word, character, byte, maximum line length.
+    Short options may be combined, as in -lw.
 
         -help        display this help and exit
         -version     output version information and exit
-        
+
         -c, -bytes
               print the byte counts
-        
+
         -m, -chars
               print the character counts
-        
+
         -l, -lines
               print the newline counts
-              
+
         -sloc
-              print the source lines of code
-              
+              print the source lines of code: those with anything besides
+              comments and white space, in the syntax of the language told
+              by the file's extension or #! line
+
+        -sloc-by-language
+              print a table of the files, blank lines, comment lines and
+              source lines of code in each language instead
+
         -o
               print the occurrences of a particular letter, word or phrase
-              
+
+        -files0-from=F
+              read input from the files specified by NUL-terminated names in
+              file F; if F is - then read names from standard input
+
         -L, -max-line-length
-              print the length of the longest line
-              
+              print the maximum display width
+
+        -total=WHEN
+              when to print a line with total counts; WHEN can be: auto,
+              always, only, never
+
         -w, -words
               print the word counts
+
+        -j N
+              count up to N files at once
 `
 	version_text = `
     wc (go-coreutils) 0.1
 
     Copyright (C) 2014, The GO-Coreutils Developers.
     This program comes with ABSOLUTELY NO WARRANTY; for details see
-    LICENSE. This is free software, and you are welcome to redistribute 
+    LICENSE. This is free software, and you are welcome to redistribute
     it under certain conditions in LICENSE.
 `
 )
@@ -79,55 +107,60 @@ func printAndExit(message string) {
 	os.Exit(0)
 }
 
-// openFile will open the file and check for errors, then return the file
-func openFile(s string) *os.File {
-	fi, err := os.Open(s)
-	if err != nil {
-		printAndExit("wc: " + s + ": No such file or directory")
-	}
-	return fi
+// usageError prints the error with a pointer to the help, and exits.
+func usageError(format string, a ...interface{}) {
+	fmt.Fprintf(os.Stderr, "wc: "+format+"\n", a...)
+	fmt.Fprintln(os.Stderr, "Try 'wc -help' for more information.")
+	os.Exit(1)
 }
 
-// slocCounter counts the source lines of code
-func slocCounter(buffer []byte, count int) int {
-	// Returns true if the input line is not an empty.
-	isNotEmpty := func(buffer []byte) bool {
-		return !(len(buffer) < 1)
-	}
-	// Returns true if the input line is a line of code
-	isCode := func(line string) bool {
-		line = strings.Replace(line, "\t", "", -1)
-		line = strings.Replace(line, " ", "", -1)
-		prefix := strings.HasPrefix // make an alias for 'HasPrefix'
-		return prefix(line, "//") || prefix(line, " *") ||
-			prefix(line, "/*") || prefix(line, "*/") ||
-			prefix(line, "{") && len(line) == 1 ||
-			prefix(line, "}") && len(line) == 1 ||
-			prefix(line, ")") || prefix(line, "package") ||
-			prefix(line, "import") || prefix(line, "var (") ||
-			prefix(line, "const (")
+// Returns the error's underlying message, capitalised as the C library's
+// messages are.
+func errorString(err error) string {
+	if pathError, ok := err.(*os.PathError); ok {
+		err = pathError.Err
 	}
-	if isNotEmpty(buffer) && !isCode(string(buffer)) {
-		count++
-	}
-	return count
+	message := err.Error()
+	return strings.ToUpper(message[:1]) + message[1:]
 }
 
+// The counts to print, chosen in init. They are printed in this order.
+var (
+	showLines       bool
+	showWords       bool
+	showCharacters  bool
+	showBytes       bool
+	showMaxLength   bool
+	showSLOC        bool
+	showOccurrences bool
+)
+
 // occurrenceCounter counts the number of occurrences of occurrenceRef.
 func occurrenceCounter(buffer []byte) int {
 	return bytes.Count(buffer, []byte(*occurrenceRef))
 }
 
-// wordcount returns the number of words by splitting the buffer's fields.
-func wordCount(buffer []byte) int {
-	return len(strings.Fields(string(buffer)))
+// The ranges of characters that terminals show two columns wide: the East
+// Asian wide and fullwidth characters, and emoji.
+var wideRanges = []struct{ first, last rune }{
+	{0x1100, 0x115F}, {0x2E80, 0x303E}, {0x3041, 0x33FF}, {0x3400, 0x4DBF},
+	{0x4E00, 0x9FFF}, {0xA000, 0xA4CF}, {0xAC00, 0xD7A3}, {0xF900, 0xFAFF},
+	{0xFE30, 0xFE4F}, {0xFF00, 0xFF60}, {0xFFE0, 0xFFE6}, {0x1F300, 0x1F64F},
+	{0x1F900, 0x1F9FF}, {0x20000, 0x2FFFD}, {0x30000, 0x3FFFD},
 }
 
-/* characterCount counts the number of characters in the bytes buffer by
- * sending a bytes slice of the buffer to the RuneCount function in utf8. This
- * gives support for non-ASCII characters that don't fit inside of a byte. */
-func characterCount(buffer []byte) int {
-	return utf8.RuneCount(buffer)
+// Returns the number of columns a terminal shows the printable character
+// in: none for combining marks and format characters, two for wide ones.
+func runeWidth(r rune) int {
+	if unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) {
+		return 0
+	}
+	for _, wide := range wideRanges {
+		if r >= wide.first && r <= wide.last {
+			return 2
+		}
+	}
+	return 1
 }
 
 // wcstat stores statistics for each file processsed by wc
@@ -140,94 +173,544 @@ type wcstat struct {
 	sloc       int
 	occurences int
 	fileName   string
+
+	blank    int    // The blank lines, for -sloc-by-language
+	comments int    // The lines of nothing but comments, likewise
+	language string // The language -sloc took the file to be in
+
+	inWord    bool       // Whether the last character read was part of a word
+	lineWidth int        // The display width of the line so far
+	line      []byte     // The line so far, for -sloc and -o
+	source    *slocState // The comment or string left open, for -sloc
+}
+
+// endLine records the width of the line that has ended, and counts its
+// source lines and occurrences if they are asked for.
+func (wc *wcstat) endLine() {
+	if wc.maxLength < wc.lineWidth {
+		wc.maxLength = wc.lineWidth
+	}
+	wc.lineWidth = 0
+	if showSLOC {
+		switch wc.source.classify(string(wc.line)) {
+		case LINE_CODE:
+			wc.sloc++
+		case LINE_COMMENT:
+			wc.comments++
+		default:
+			wc.blank++
+		}
+	}
+	if showOccurrences {
+		wc.occurences += occurrenceCounter(wc.line)
+	}
+	wc.line = wc.line[:0]
 }
 
-// maxLineLength determines the maximum line length for the entire file.
-// NOTE: GNU wc counts by bytes rather than by runes, which is not accurate. It
-// also does not correctly detect tabs, giving a false line length size.
-func (wc *wcstat) maxLineLength(buffer []byte) {
-	length := utf8.RuneCount(buffer)
-	if wc.maxLength < length {
-		wc.maxLength = length
-	}
-}
-
-// processStats will process the input buffer and append the obtained stats
-// to the wcstat struct.
-func (wc *wcstat) getStats(buffer []byte) {
-	switch {
-	case *countBytes || *countBytesL:
-		wc.bytes += len(buffer) + 1
-	case *countCharacters || *countCharactersL:
-		wc.characters += characterCount(buffer) + 1
-	case *countLines || *countLinesL:
-		wc.lines++
-	case *maxLineLength || *maxLineLengthL:
-		wc.maxLineLength(buffer)
-	case *countWords || *countWordsL:
-		wc.words += wordCount(buffer)
-	case *countSLOC:
-		wc.sloc += slocCounter(buffer, 0)
-	case len(*occurrenceRef) != 0: // Count occurences if not empty.
-		wc.occurences += occurrenceCounter(buffer)
-	default: // Print all if no argument is given.
-		wc.lines++
-		wc.words += wordCount(buffer)
-		wc.bytes += len(buffer)
-	}
-}
-
-// printStats prints the statistics for the current file.
-func (wc *wcstat) printStats() {
-	switch {
-	case *countBytes || *countBytesL:
-		fmt.Println(wc.bytes, wc.fileName)
-	case *countCharacters || *countCharactersL:
-		fmt.Println(wc.characters, wc.fileName)
-	case *countLines || *countLinesL:
-		fmt.Println(wc.lines, wc.fileName)
-	case *maxLineLength || *maxLineLengthL:
-		fmt.Println(wc.maxLength, wc.fileName)
-	case *countWords || *countWordsL:
-		fmt.Println(wc.words, wc.fileName)
-	case *countSLOC:
-		fmt.Println(wc.sloc, wc.fileName)
-	case len(*occurrenceRef) != 0: // Count occurences if not empty.
-		fmt.Println(wc.occurences, wc.fileName)
-	default: // Print all if no argument is given.
-		fmt.Println(wc.lines, wc.words, wc.bytes+wc.lines, wc.fileName)
-	}
-}
-
-// scanFile scans each file, line by line, gathering statistics using a scanner.
-func (wc *wcstat) scanFile(scanner *bufio.Scanner) {
-	for scanner.Scan() {
-		wc.getStats(scanner.Bytes())
+const CHUNK_SIZE = 64 * 1024 // Bytes read at a time
+
+// The ASCII white space characters, which end words.
+var asciiSpace = [utf8.RuneSelf]bool{'\t': true, '\n': true, '\v': true, '\f': true, '\r': true, ' ': true}
+
+// Returns true if the character ends words.
+func isSpace(r rune) bool {
+	if r < utf8.RuneSelf {
+		return asciiSpace[r]
 	}
+	return unicode.IsSpace(r)
 }
 
-func main() {
-	if flag.NArg() == 0 || flag.Arg(0) == "-" {
-		var wc wcstat
-		wc.scanFile(bufio.NewScanner(os.Stdin))
-		wc.printStats()
+// Returns true if the counts can be had from the newlines alone.
+func linesOnly() bool {
+	return !showWords && !showCharacters && !showMaxLength && !showSLOC && !showOccurrences
+}
+
+/* count reads the input to its end, a chunk at a time, and counts
+ * everything at once. Newlines alone are counted with bytes.Count, and
+ * words with little more, leaving the full work of decoding characters to
+ * the counts that need it. The byte count of a regular file, on its own,
+ * is its size, and the file is not read at all. For -sloc, each line is
+ * classified by the syntax of the file's language. */
+func (wc *wcstat) count(input *os.File) error {
+	if showSLOC {
+		wc.source = newSlocState(wc.fileName)
+		defer func() {
+			if wc.language = plainText.name; wc.source.language != nil {
+				wc.language = wc.source.language.name
+			}
+		}()
+	}
+	if linesOnly() && !showLines {
+		if info, err := input.Stat(); err == nil && info.Mode().IsRegular() && info.Size() > 0 {
+			if offset, err := input.Seek(0, io.SeekCurrent); err == nil {
+				if offset < info.Size() {
+					wc.bytes = int(info.Size() - offset)
+				}
+				return nil
+			}
+		}
+	}
+
+	buffer := make([]byte, CHUNK_SIZE+utf8.UTFMax)
+	carry := 0 // The bytes of a character split between chunks
+	for {
+		n, err := input.Read(buffer[carry : carry+CHUNK_SIZE])
+		chunk := buffer[:carry+n]
+		atEOF := err == io.EOF
+		switch {
+		case linesOnly():
+			wc.bytes += n
+			wc.lines += bytes.Count(chunk, []byte{'\n'})
+		case !showCharacters && !showMaxLength && !showSLOC && !showOccurrences:
+			carry = wc.scanWords(chunk, atEOF)
+		default:
+			carry = wc.scan(chunk, atEOF)
+		}
+		copy(buffer, chunk[len(chunk)-carry:])
+
+		if atEOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if wc.lineWidth > 0 || len(wc.line) > 0 {
+		wc.endLine()
+	}
+	return nil
+}
+
+/* scanWords counts the bytes, lines and words of the chunk, and returns
+ * how many bytes at its end are the start of a character that the next
+ * chunk finishes. Only characters beyond ASCII are decoded, to see if they
+ * are spaces. Bytes that are not valid UTF-8 neither start nor end words. */
+func (wc *wcstat) scanWords(chunk []byte, atEOF bool) int {
+	wc.lines += bytes.Count(chunk, []byte{'\n'})
+	inWord, words := wc.inWord, 0
+	for index := 0; index < len(chunk); {
+		b := chunk[index]
+		if b < utf8.RuneSelf {
+			if asciiSpace[b] {
+				inWord = false
+			} else if !inWord {
+				inWord = true
+				words++
+			}
+			index++
+			continue
+		}
+		if !atEOF && !utf8.FullRune(chunk[index:]) {
+			wc.bytes += index
+			wc.words += words
+			wc.inWord = inWord
+			wc.lines -= bytes.Count(chunk[index:], []byte{'\n'})
+			return len(chunk) - index
+		}
+		r, size := utf8.DecodeRune(chunk[index:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			// Leaves the word, or the space, as it was.
+		case isSpace(r):
+			inWord = false
+		case !inWord:
+			inWord = true
+			words++
+		}
+		index += size
+	}
+	wc.bytes += len(chunk)
+	wc.words += words
+	wc.inWord = inWord
+	return 0
+}
+
+/* scan counts everything in the chunk, one character at a time, and
+ * returns how many bytes at its end are the start of a character that the
+ * next chunk finishes. Bytes that are not valid UTF-8 are counted as bytes,
+ * but are not characters, take no columns, and neither start nor end
+ * words. Lines
+ * are measured as a terminal shows them, with tabs stopping every eight
+ * columns and carriage returns and form feeds starting over. */
+func (wc *wcstat) scan(chunk []byte, atEOF bool) int {
+	keepLine := showSLOC || showOccurrences
+	for index := 0; index < len(chunk); {
+		r, size := rune(chunk[index]), 1
+		if r >= utf8.RuneSelf {
+			if !atEOF && !utf8.FullRune(chunk[index:]) {
+				return len(chunk) - index
+			}
+			r, size = utf8.DecodeRune(chunk[index:])
+		}
+		if keepLine && r != '\n' {
+			wc.line = append(wc.line, chunk[index:index+size]...)
+		}
+		index += size
+		wc.bytes += size
+
+		if r == utf8.RuneError && size == 1 {
+			continue
+		}
+		wc.characters++
+
+		switch {
+		case r == '\n':
+			wc.lines++
+			wc.endLine()
+		case r == '\r' || r == '\f':
+			if wc.maxLength < wc.lineWidth {
+				wc.maxLength = wc.lineWidth
+			}
+			wc.lineWidth = 0
+		case r == '\t':
+			wc.lineWidth += 8 - wc.lineWidth%8
+		case r < utf8.RuneSelf:
+			if r >= ' ' && r != 0x7F {
+				wc.lineWidth++
+			}
+		case unicode.IsGraphic(r) || unicode.Is(unicode.Cf, r):
+			wc.lineWidth += runeWidth(r)
+		}
+
+		if isSpace(r) {
+			wc.inWord = false
+		} else if !wc.inWord {
+			wc.inWord = true
+			wc.words++
+		}
+	}
+	return 0
+}
+
+// values returns the counts to print, in the order they are printed.
+func (wc *wcstat) values() []int {
+	var values []int
+	for _, column := range []struct {
+		show  bool
+		value int
+	}{
+		{showLines, wc.lines},
+		{showWords, wc.words},
+		{showCharacters, wc.characters},
+		{showBytes, wc.bytes},
+		{showMaxLength, wc.maxLength},
+		{showSLOC, wc.sloc},
+		{showOccurrences, wc.occurences},
+	} {
+		if column.show {
+			values = append(values, column.value)
+		}
+	}
+	return values
+}
+
+// add adds the counts of the other file to these, as for the total.
+func (wc *wcstat) add(other *wcstat) {
+	wc.bytes += other.bytes
+	wc.characters += other.characters
+	wc.lines += other.lines
+	wc.words += other.words
+	wc.sloc += other.sloc
+	wc.occurences += other.occurences
+	if wc.maxLength < other.maxLength {
+		wc.maxLength = other.maxLength
+	}
+}
+
+/* printStats prints the statistics of each file, right-aligned in columns
+ * as wide as the largest count printed, each followed by the file's name,
+ * if it has one. */
+func printStats(stats []*wcstat) {
+	width := 1
+	for _, wc := range stats {
+		for _, value := range wc.values() {
+			if digits := len(fmt.Sprint(value)); digits > width {
+				width = digits
+			}
+		}
+	}
+
+	for _, wc := range stats {
+		var fields []string
+		for _, value := range wc.values() {
+			fields = append(fields, fmt.Sprintf("%*d", width, value))
+		}
+		if wc.fileName != "" {
+			fields = append(fields, wc.fileName)
+		}
+		fmt.Println(strings.Join(fields, " "))
+	}
+}
+
+/* printLanguages prints a table of the files, blank lines, comment lines
+ * and source lines of code in each language, most code first, and a total
+ * line as -total says. The counts are right-aligned under their headings. */
+func printLanguages(stats []*wcstat) {
+	byLanguage := map[string]*[4]int{}
+	var names []string
+	var total [4]int
+	for _, wc := range stats {
+		counts, ok := byLanguage[wc.language]
+		if !ok {
+			counts = &[4]int{}
+			byLanguage[wc.language] = counts
+			names = append(names, wc.language)
+		}
+		for index, value := range [4]int{1, wc.blank, wc.comments, wc.sloc} {
+			counts[index] += value
+			total[index] += value
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := byLanguage[names[i]], byLanguage[names[j]]
+		if a[3] != b[3] {
+			return a[3] > b[3]
+		}
+		return names[i] < names[j]
+	})
+
+	rows := [][]string{{"Language", "Files", "Blank", "Comment", "Code"}}
+	addRow := func(name string, counts [4]int) {
+		rows = append(rows, []string{name, fmt.Sprint(counts[0]), fmt.Sprint(counts[1]),
+			fmt.Sprint(counts[2]), fmt.Sprint(counts[3])})
+	}
+	if *totalWhen != "only" {
+		for _, name := range names {
+			addRow(name, *byLanguage[name])
+		}
+	}
+	if *totalWhen == "always" || *totalWhen == "only" || (*totalWhen == "auto" && len(names) > 1) {
+		addRow("total", total)
+	}
+
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for column, field := range row {
+			if len(field) > widths[column] {
+				widths[column] = len(field)
+			}
+		}
+	}
+	for _, row := range rows {
+		line := fmt.Sprintf("%-*s", widths[0], row[0])
+		for column := 1; column < len(row); column++ {
+			line += fmt.Sprintf("  %*s", widths[column], row[column])
+		}
+		fmt.Println(line)
+	}
+}
+
+/* countFile counts the file, or standard input if the name is -. Errors are
+ * printed as they happen; a file that cannot be opened has no statistics,
+ * and one that cannot be read has those of what was read. */
+func countFile(name string) (*wcstat, bool) {
+	wc := &wcstat{fileName: name}
+	input := os.Stdin
+	if name != "-" {
+		file, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "wc: %s: %s\n", name, errorString(err))
+			return nil, false
+		}
+		defer file.Close()
+		input = file
+	}
+	if err := wc.count(input); err != nil {
+		fmt.Fprintf(os.Stderr, "wc: %s: %s\n", name, errorString(err))
+		return wc, false
+	}
+	return wc, true
+}
+
+/* countFiles counts the files, up to -j of them at once, and returns their
+ * statistics in the order of the names, and whether all were counted. */
+func countFiles(names []string) ([]*wcstat, bool) {
+	stats := make([]*wcstat, len(names))
+	var failed int32
+	indexes := make(chan int)
+	var wait sync.WaitGroup
+	for worker := 0; worker < *jobs; worker++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			for index := range indexes {
+				wc, ok := countFile(names[index])
+				if !ok {
+					atomic.StoreInt32(&failed, 1)
+				}
+				stats[index] = wc
+			}
+		}()
+	}
+	for index := range names {
+		indexes <- index
+	}
+	close(indexes)
+	wait.Wait()
+	return stats, failed == 0
+}
+
+/* readFiles0From returns the names in the file of NUL-terminated names, or
+ * in standard input if the name is -, and how many names there were. Empty
+ * names are reported and left out, as is - when the names come from
+ * standard input. */
+func readFiles0From(name string) ([]string, int, bool) {
+	var contents []byte
+	var err error
+	if name == "-" {
+		contents, err = ioutil.ReadAll(os.Stdin)
 	} else {
-		for file := 0; file < flag.NArg(); file++ {
-			wc := wcstat{fileName: flag.Arg(file)}
-			wc.scanFile(bufio.NewScanner(openFile(flag.Arg(file))))
-			wc.printStats()
+		contents, err = ioutil.ReadFile(name)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "wc: cannot open '%s' for reading: %s\n", name, errorString(err))
+		os.Exit(1)
+	}
+
+	ok := true
+	var names []string
+	fields := strings.Split(string(contents), "\x00")
+	if len(fields) > 0 && fields[len(fields)-1] == "" {
+		fields = fields[:len(fields)-1]
+	}
+	for index, file := range fields {
+		switch {
+		case file == "":
+			fmt.Fprintf(os.Stderr, "wc: %s:%d: invalid zero-length file name\n", name, index+1)
+			ok = false
+		case file == "-" && name == "-":
+			fmt.Fprintln(os.Stderr, "wc: when reading file names from stdin, no file name of '-' allowed")
+			ok = false
+		default:
+			names = append(names, file)
+		}
+	}
+	return names, len(fields), ok
+}
+
+func main() {
+	processFlags()
+
+	status := 0
+	names := flag.Args()
+	given := len(names)
+	if *files0From != "" {
+		if flag.NArg() > 0 {
+			usageError("extra operand '%s'\nfile operands cannot be combined with --files0-from", flag.Arg(0))
+		}
+		var ok bool
+		if names, given, ok = readFiles0From(*files0From); !ok {
+			status = 1
+		}
+	}
+
+	var stats []*wcstat
+	if len(names) == 0 && *files0From == "" {
+		wc := &wcstat{}
+		if err := wc.count(os.Stdin); err != nil {
+			fmt.Fprintf(os.Stderr, "wc: -: %s\n", errorString(err))
+			status = 1
+		}
+		stats = append(stats, wc)
+	}
+	results, ok := countFiles(names)
+	if !ok {
+		status = 1
+	}
+	for _, wc := range results {
+		if wc != nil {
+			stats = append(stats, wc)
+		}
+	}
+
+	if *slocByLanguage {
+		printLanguages(stats)
+		os.Exit(status)
+	}
+
+	total := &wcstat{fileName: "total"}
+	for _, wc := range stats {
+		total.add(wc)
+	}
+	switch *totalWhen {
+	case "always":
+		stats = append(stats, total)
+	case "only":
+		total.fileName = ""
+		stats = []*wcstat{total}
+	case "auto":
+		if given > 1 {
+			stats = append(stats, total)
+		}
+	}
+	printStats(stats)
+	os.Exit(status)
+}
+
+// The flags that take their value from the following argument.
+var valueFlags = map[string]bool{"o": true, "files0-from": true, "total": true, "j": true}
+
+/* expandShortFlags splits combined short flags, as in -lw, into separate
+ * ones, since the flag package only takes them one at a time. The value of
+ * a flag given as the following argument is passed on as it is. */
+func expandShortFlags(arguments []string) []string {
+	var expanded []string
+	for index := 0; index < len(arguments); index++ {
+		argument := arguments[index]
+		if argument == "--" || !strings.HasPrefix(argument, "-") || argument == "-" {
+			return append(expanded, arguments[index:]...)
+		}
+		if valueFlags[strings.TrimLeft(argument, "-")] && index+1 < len(arguments) {
+			expanded = append(expanded, argument, arguments[index+1])
+			index++
+			continue
+		}
+		letters := argument[1:]
+		if len(letters) < 2 || strings.Trim(letters, "cmlwL") != "" {
+			expanded = append(expanded, argument)
+			continue
+		}
+		for _, letter := range letters {
+			expanded = append(expanded, "-"+string(letter))
 		}
 	}
+	return expanded
 }
 
-func init() {
+// processFlags parses the command line, with combined short flags split,
+// and decides which counts to show.
+func processFlags() {
 	help := flag.Bool("help", false, help_text)
 	version := flag.Bool("version", false, version_text)
-	flag.Parse()
+	flag.CommandLine.Parse(expandShortFlags(os.Args[1:]))
 	if *help {
 		printAndExit(help_text)
 	}
 	if *version {
 		printAndExit(version_text)
 	}
+
+	showLines = *countLines || *countLinesL
+	showWords = *countWords || *countWordsL
+	showCharacters = *countCharacters || *countCharactersL
+	showBytes = *countBytes || *countBytesL
+	showMaxLength = *maxLineLength || *maxLineLengthL
+	showSLOC = *countSLOC || *slocByLanguage
+	showOccurrences = len(*occurrenceRef) != 0
+
+	// Print all if no count is asked for.
+	if !showLines && !showWords && !showCharacters && !showBytes && !showMaxLength && !showSLOC && !showOccurrences {
+		showLines, showWords, showBytes = true, true, true
+	}
+
+	if *jobs < 1 {
+		usageError("invalid number of jobs: '%d'", *jobs)
+	}
+
+	switch *totalWhen {
+	case "auto", "always", "only", "never":
+	default:
+		usageError("invalid argument '%s' for '--total'\nValid arguments are:\n"+
+			"  - 'auto'\n  - 'always'\n  - 'only'\n  - 'never'", *totalWhen)
+	}
 }
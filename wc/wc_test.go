@@ -0,0 +1,204 @@
+//
+// wc_test.go (go-coreutils) 0.1
+// Copyright (C) 2014, The GO-Coreutils Developers.
+//
+// Written By: Michael Murphy
+//
+package main
+
+import "bytes"
+import "io/ioutil"
+import "os"
+import "strings"
+import "testing"
+
+// Text with characters of every length, a space beyond ASCII, a byte that
+// is not UTF-8, and a tab, which -L measures to the next stop.
+const sample = "héllo　wörld\t日本\n\xffx 🎉 end\n"
+
+// show sets the counts to make, as the flags would, until the test ends.
+func show(t testing.TB, lines, words, characters, bytes, maxLength bool) {
+	saved := []bool{showLines, showWords, showCharacters, showBytes, showMaxLength}
+	showLines, showWords, showCharacters, showBytes, showMaxLength = lines, words, characters, bytes, maxLength
+	t.Cleanup(func() {
+		showLines, showWords, showCharacters, showBytes, showMaxLength = saved[0], saved[1], saved[2], saved[3], saved[4]
+	})
+}
+
+/* scanSplit counts the input in two chunks, split at the offset, with the
+ * scanning function, carrying the bytes it leaves over into the second
+ * chunk as count does. */
+func scanSplit(input []byte, split int, scan func(wc *wcstat, chunk []byte, atEOF bool) int) *wcstat {
+	wc := &wcstat{}
+	first := append([]byte(nil), input[:split]...)
+	carry := scan(wc, first, false)
+	second := append(first[len(first)-carry:], input[split:]...)
+	scan(wc, second, true)
+	if wc.lineWidth > 0 || len(wc.line) > 0 {
+		wc.endLine()
+	}
+	return wc
+}
+
+func TestScanWordsSplit(t *testing.T) {
+	input := []byte(sample)
+	whole := scanSplit(input, 0, (*wcstat).scanWords)
+	if whole.lines != 2 || whole.words != 6 || whole.bytes != len(input) {
+		t.Fatalf("got %d lines, %d words, %d bytes; want 2, 6, %d", whole.lines, whole.words, whole.bytes, len(input))
+	}
+	for split := 1; split < len(input); split++ {
+		wc := scanSplit(input, split, (*wcstat).scanWords)
+		if wc.lines != whole.lines || wc.words != whole.words || wc.bytes != whole.bytes {
+			t.Errorf("split at %d: got %d lines, %d words, %d bytes; want %d, %d, %d",
+				split, wc.lines, wc.words, wc.bytes, whole.lines, whole.words, whole.bytes)
+		}
+	}
+}
+
+func TestScanSplit(t *testing.T) {
+	input := []byte(sample)
+	whole := scanSplit(input, 0, (*wcstat).scan)
+	if whole.lines != 2 || whole.words != 6 || whole.characters != 23 || whole.maxLength != 20 {
+		t.Fatalf("got %d lines, %d words, %d characters, width %d; want 2, 6, 23, 20",
+			whole.lines, whole.words, whole.characters, whole.maxLength)
+	}
+	for split := 1; split < len(input); split++ {
+		wc := scanSplit(input, split, (*wcstat).scan)
+		if wc.lines != whole.lines || wc.words != whole.words || wc.characters != whole.characters ||
+			wc.bytes != whole.bytes || wc.maxLength != whole.maxLength {
+			t.Errorf("split at %d: got %d lines, %d words, %d characters, %d bytes, width %d; want %d, %d, %d, %d, %d",
+				split, wc.lines, wc.words, wc.characters, wc.bytes, wc.maxLength,
+				whole.lines, whole.words, whole.characters, whole.bytes, whole.maxLength)
+		}
+	}
+}
+
+// Returns a temporary file with the content, removed when the test ends.
+func tempFile(t testing.TB, content []byte) *os.File {
+	file, err := ioutil.TempFile("", "wc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		file.Close()
+		os.Remove(file.Name())
+	})
+	if _, err := file.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// countFrom counts the file from its start.
+func countFrom(t testing.TB, file *os.File) *wcstat {
+	if _, err := file.Seek(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	wc := &wcstat{}
+	if err := wc.count(file); err != nil {
+		t.Fatal(err)
+	}
+	return wc
+}
+
+// TestCountChunkBoundary counts files in which a character beyond ASCII, a
+// space or not, is split across the end of the first chunk read.
+func TestCountChunkBoundary(t *testing.T) {
+	for _, character := range []string{"é", "　", "日", "🎉"} {
+		for before := 1; before < len(character); before++ {
+			var content bytes.Buffer
+			content.WriteString(strings.Repeat("a", CHUNK_SIZE-before))
+			content.WriteString(character + "b\n")
+			words, characters := 1, CHUNK_SIZE-before+3
+			if character == "　" {
+				words = 2
+			}
+			file := tempFile(t, content.Bytes())
+
+			show(t, true, true, false, true, false)
+			wc := countFrom(t, file)
+			if wc.lines != 1 || wc.words != words || wc.bytes != content.Len() {
+				t.Errorf("-w, %q split after %d bytes: got %d lines, %d words, %d bytes; want 1, %d, %d",
+					character, before, wc.lines, wc.words, wc.bytes, words, content.Len())
+			}
+
+			show(t, true, true, true, true, false)
+			wc = countFrom(t, file)
+			if wc.lines != 1 || wc.words != words || wc.characters != characters || wc.bytes != content.Len() {
+				t.Errorf("-m, %q split after %d bytes: got %d lines, %d words, %d characters, %d bytes; want 1, %d, %d, %d",
+					character, before, wc.lines, wc.words, wc.characters, wc.bytes, words, characters, content.Len())
+			}
+		}
+	}
+}
+
+// Returns 32 MiB of text in many lines and words, a tenth of them beyond
+// ASCII.
+func largeInput() []byte {
+	line := []byte("The quick brown fox jumps over the lazy dog, 日本語 and ünïcödé too.\n")
+	return bytes.Repeat(line, 32<<20/len(line))
+}
+
+// benchmarkCount counts the large input in a file with the counts set.
+func benchmarkCount(b *testing.B, lines, words, characters bool) {
+	input := largeInput()
+	file := tempFile(b, input)
+	show(b, lines, words, characters, false, false)
+	b.SetBytes(int64(len(input)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		countFrom(b, file)
+	}
+}
+
+func BenchmarkCountLines(b *testing.B) { benchmarkCount(b, true, false, false) }
+
+func BenchmarkCountWords(b *testing.B) { benchmarkCount(b, false, true, false) }
+
+func BenchmarkCountCharacters(b *testing.B) { benchmarkCount(b, false, false, true) }
+
+// TestClassifyShebang classifies the first line of files whose language
+// does or does not take #! for a comment.
+func TestClassifyShebang(t *testing.T) {
+	for _, test := range []struct {
+		name, line string
+		kind       int
+		language   string
+	}{
+		{"script", "#!/usr/bin/env python3", LINE_COMMENT, "Python"},
+		{"script.sh", "#!/bin/sh", LINE_COMMENT, "Shell"},
+		{"unknown", "#!/usr/bin/frobnicate", LINE_COMMENT, "Text"},
+		{"lib.rs", "#![no_std]", LINE_CODE, "Rust"},
+		{"main.c", "#!", LINE_CODE, "C"},
+	} {
+		s := newSlocState(test.name)
+		if kind := s.classify(test.line); kind != test.kind {
+			t.Errorf("%s: %q classified as %d, want %d", test.name, test.line, kind, test.kind)
+		}
+		if s.language == nil {
+			s.classify("")
+		}
+		if s.language.name != test.language {
+			t.Errorf("%s: taken for %s, want %s", test.name, s.language.name, test.language)
+		}
+	}
+}
+
+func TestExpandShortFlags(t *testing.T) {
+	for _, test := range []struct {
+		arguments, want []string
+	}{
+		{[]string{"-lw", "a"}, []string{"-l", "-w", "a"}},
+		{[]string{"-j", "4", "-lw", "a", "b"}, []string{"-j", "4", "-l", "-w", "a", "b"}},
+		{[]string{"--total", "never", "-cm"}, []string{"--total", "never", "-c", "-m"}},
+		{[]string{"-o", "-lw", "-L"}, []string{"-o", "-lw", "-L"}},
+		{[]string{"-j=2", "-lw"}, []string{"-j=2", "-l", "-w"}},
+		{[]string{"-lw", "--", "-cm"}, []string{"-l", "-w", "--", "-cm"}},
+		{[]string{"-files0-from"}, []string{"-files0-from"}},
+	} {
+		got := expandShortFlags(test.arguments)
+		if strings.Join(got, " ") != strings.Join(test.want, " ") {
+			t.Errorf("expandShortFlags(%q) = %q, want %q", test.arguments, got, test.want)
+		}
+	}
+}